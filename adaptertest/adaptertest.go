@@ -0,0 +1,683 @@
+// Package adaptertest is a conformance suite for guam adapters. The adapters
+// in this repository that store users run it, and custom adapters can too:
+//
+//	func TestConformance(t *testing.T) {
+//		adaptertest.Run(t, func(t *testing.T) auth.AdapterWithGetter {
+//			return MyAdapter(...)
+//		})
+//	}
+//
+// The suite expects the user table to have a text "username" column and the
+// session table a text "country" column next to the columns guam requires.
+// Keys only use the required columns.
+//
+// Adapters that only store sessions, like the redis adapter, run the session
+// tests with RunSessions, and the whole suite behind the composite adapter.
+package adaptertest
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/seatedro/guam/auth"
+	"github.com/seatedro/guam/utils"
+)
+
+// Factory returns the adapter under test. It is called once per test, so it
+// may hand out a fresh adapter every time or share one; the suite only uses
+// random ids and never assumes a table is empty.
+type Factory func(t *testing.T) auth.AdapterWithGetter
+
+// SessionAdapter is the part of a guam adapter storing sessions.
+type SessionAdapter interface {
+	GetSession(sessionId string) (*auth.SessionSchema, error)
+	GetSessionsByUserId(userId string) ([]auth.SessionSchema, error)
+	SetSession(session auth.SessionSchema) error
+	DeleteSession(sessionId string) error
+	DeleteSessionsByUserId(userId string) error
+	UpdateSession(sessionId string, partialSession map[string]any) error
+}
+
+// SessionFactory returns the session adapter under test, like Factory.
+type SessionFactory func(t *testing.T) SessionAdapter
+
+type test struct {
+	name string
+	run  func(t *testing.T, adapter auth.AdapterWithGetter)
+}
+
+// sessionTest is a test that only needs a session adapter. newUserId returns
+// the id of a user sessions can belong to.
+type sessionTest struct {
+	name string
+	run  func(t *testing.T, adapter SessionAdapter, newUserId func(t *testing.T) string)
+}
+
+var tests = []test{
+	{"GetUser/Missing", testGetUserMissing},
+	{"SetUser", testSetUser},
+	{"SetUser/WithKey", testSetUserWithKey},
+	{"SetUser/DuplicateKeyId", testSetUserDuplicateKeyId},
+	{"UpdateUser", testUpdateUser},
+	{"UpdateUser/Id", testUpdateUserId},
+	{"DeleteUser", testDeleteUser},
+	{"DeleteUser/Missing", testDeleteUserMissing},
+	{"SetSession/InvalidUserId", testSetSessionInvalidUserId},
+	{"DeleteSessionsByUserId/KeepsUser", testDeleteSessionsByUserIdKeepsUser},
+	{"GetKey/Missing", testGetKeyMissing},
+	{"SetKey", testSetKey},
+	{"SetKey/NullPassword", testSetKeyNullPassword},
+	{"SetKey/DuplicateKeyId", testSetKeyDuplicateKeyId},
+	{"SetKey/InvalidUserId", testSetKeyInvalidUserId},
+	{"GetKeysByUserId", testGetKeysByUserId},
+	{"UpdateKey", testUpdateKey},
+	{"UpdateKey/Id", testUpdateKeyId},
+	{"UpdateKey/DuplicateKeyId", testUpdateKeyDuplicateKeyId},
+	{"DeleteKey", testDeleteKey},
+	{"DeleteKeysByUserId", testDeleteKeysByUserId},
+	{"GetSessionAndUser", testGetSessionAndUser},
+	{"GetSessionAndUser/Missing", testGetSessionAndUserMissing},
+}
+
+var sessionTests = []sessionTest{
+	{"GetSession/Missing", testGetSessionMissing},
+	{"SetSession", testSetSession},
+	{"GetSessionsByUserId", testGetSessionsByUserId},
+	{"UpdateSession", testUpdateSession},
+	{"UpdateSession/Id", testUpdateSessionId},
+	{"DeleteSession", testDeleteSession},
+	{"DeleteSessionsByUserId", testDeleteSessionsByUserId},
+}
+
+// Run runs the conformance suite against the adapters returned by factory,
+// each test in its own subtest.
+func Run(t *testing.T, factory Factory) {
+	t.Helper()
+	for _, tc := range sessionTests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			adapter := factory(t)
+			tc.run(t, adapter, func(t *testing.T) string {
+				return createUser(t, adapter).ID
+			})
+		})
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			tc.run(t, factory(t))
+		})
+	}
+}
+
+// RunSessions runs the tests of the suite that only need sessions against
+// the session adapters returned by factory. Sessions belong to random user
+// ids, so the adapter must not check that their user exists.
+func RunSessions(t *testing.T, factory SessionFactory) {
+	t.Helper()
+	for _, tc := range sessionTests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			tc.run(t, factory(t), func(t *testing.T) string {
+				return randomId()
+			})
+		})
+	}
+}
+
+func randomId() string {
+	return utils.GenerateRandomString(8, "")
+}
+
+func newUser() auth.UserSchema {
+	return auth.UserSchema{
+		ID: randomId(),
+		Attributes: map[string]any{
+			"username": randomId(),
+		},
+	}
+}
+
+func newKey(userId string) auth.KeySchema {
+	hashedPassword := utils.GenerateScryptHash(randomId())
+	return auth.KeySchema{
+		ID:             randomId(),
+		UserID:         userId,
+		HashedPassword: &hashedPassword,
+	}
+}
+
+// newSession returns a session expiring in the future, so that adapters
+// expiring sessions, like the redis adapter, keep it.
+func newSession(userId string) auth.SessionSchema {
+	now := time.Now()
+	return auth.SessionSchema{
+		ID:            randomId(),
+		UserID:        userId,
+		ActiveExpires: now.Add(time.Hour).UnixMilli(),
+		IdleExpires:   now.Add(2 * time.Hour).UnixMilli(),
+		Attributes: map[string]any{
+			"country": randomId(),
+		},
+	}
+}
+
+func createUser(t *testing.T, adapter auth.AdapterWithGetter) auth.UserSchema {
+	t.Helper()
+	user := newUser()
+	if err := adapter.SetUser(user, nil); err != nil {
+		t.Fatalf("SetUser: %v", err)
+	}
+	return user
+}
+
+func createSession(t *testing.T, adapter SessionAdapter, userId string) auth.SessionSchema {
+	t.Helper()
+	session := newSession(userId)
+	if err := adapter.SetSession(session); err != nil {
+		t.Fatalf("SetSession: %v", err)
+	}
+	return session
+}
+
+func createKey(t *testing.T, adapter auth.AdapterWithGetter, userId string) auth.KeySchema {
+	t.Helper()
+	key := newKey(userId)
+	if err := adapter.SetKey(key); err != nil {
+		t.Fatalf("SetKey: %v", err)
+	}
+	return key
+}
+
+func expectGuamError(t *testing.T, err error, message auth.ErrorMessage) {
+	t.Helper()
+	var guamErr *auth.GuamError
+	if !errors.As(err, &guamErr) || guamErr.Message != message {
+		t.Fatalf("expected %s, got %v", message, err)
+	}
+}
+
+func expectUser(t *testing.T, adapter auth.AdapterWithGetter, expected auth.UserSchema) {
+	t.Helper()
+	user, err := adapter.GetUser(expected.ID)
+	if err != nil {
+		t.Fatalf("GetUser: %v", err)
+	}
+	if user == nil {
+		t.Fatalf("expected user %s, got none", expected.ID)
+	}
+	if user.ID != expected.ID || user.Attributes["username"] != expected.Attributes["username"] {
+		t.Fatalf("expected user %+v, got %+v", expected, *user)
+	}
+}
+
+func expectNoUser(t *testing.T, adapter auth.AdapterWithGetter, userId string) {
+	t.Helper()
+	user, err := adapter.GetUser(userId)
+	if err != nil {
+		t.Fatalf("GetUser: %v", err)
+	}
+	if user != nil {
+		t.Fatalf("expected no user %s, got %+v", userId, *user)
+	}
+}
+
+func expectSession(t *testing.T, adapter SessionAdapter, expected auth.SessionSchema) {
+	t.Helper()
+	session, err := adapter.GetSession(expected.ID)
+	if err != nil {
+		t.Fatalf("GetSession: %v", err)
+	}
+	if session == nil {
+		t.Fatalf("expected session %s, got none", expected.ID)
+	}
+	if !equalSessions(*session, expected) {
+		t.Fatalf("expected session %+v, got %+v", expected, *session)
+	}
+}
+
+func expectNoSession(t *testing.T, adapter SessionAdapter, sessionId string) {
+	t.Helper()
+	session, err := adapter.GetSession(sessionId)
+	if err != nil {
+		t.Fatalf("GetSession: %v", err)
+	}
+	if session != nil {
+		t.Fatalf("expected no session %s, got %+v", sessionId, *session)
+	}
+}
+
+func expectKey(t *testing.T, adapter auth.AdapterWithGetter, expected auth.KeySchema) {
+	t.Helper()
+	key, err := adapter.GetKey(expected.ID)
+	if err != nil {
+		t.Fatalf("GetKey: %v", err)
+	}
+	if key == nil {
+		t.Fatalf("expected key %s, got none", expected.ID)
+	}
+	if key.ID != expected.ID ||
+		key.UserID != expected.UserID ||
+		!equalPasswords(key.HashedPassword, expected.HashedPassword) {
+		t.Fatalf("expected key %+v, got %+v", expected, *key)
+	}
+}
+
+func expectNoKey(t *testing.T, adapter auth.AdapterWithGetter, keyId string) {
+	t.Helper()
+	key, err := adapter.GetKey(keyId)
+	if err != nil {
+		t.Fatalf("GetKey: %v", err)
+	}
+	if key != nil {
+		t.Fatalf("expected no key %s, got %+v", keyId, *key)
+	}
+}
+
+// equalSessions compares the guam columns and the "country" attribute of two
+// sessions.
+func equalSessions(a, b auth.SessionSchema) bool {
+	return a.ID == b.ID &&
+		a.UserID == b.UserID &&
+		a.ActiveExpires == b.ActiveExpires &&
+		a.IdleExpires == b.IdleExpires &&
+		a.Attributes["country"] == b.Attributes["country"]
+}
+
+func equalPasswords(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func sessionIds(sessions []auth.SessionSchema) map[string]bool {
+	ids := make(map[string]bool, len(sessions))
+	for _, session := range sessions {
+		ids[session.ID] = true
+	}
+	return ids
+}
+
+func keyIds(keys []auth.KeySchema) map[string]bool {
+	ids := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		ids[key.ID] = true
+	}
+	return ids
+}
+
+func testGetUserMissing(t *testing.T, adapter auth.AdapterWithGetter) {
+	expectNoUser(t, adapter, randomId())
+}
+
+func testSetUser(t *testing.T, adapter auth.AdapterWithGetter) {
+	user := createUser(t, adapter)
+	expectUser(t, adapter, user)
+}
+
+func testSetUserWithKey(t *testing.T, adapter auth.AdapterWithGetter) {
+	user := newUser()
+	key := newKey(user.ID)
+	if err := adapter.SetUser(user, &key); err != nil {
+		t.Fatalf("SetUser: %v", err)
+	}
+	expectUser(t, adapter, user)
+	expectKey(t, adapter, key)
+}
+
+func testSetUserDuplicateKeyId(t *testing.T, adapter auth.AdapterWithGetter) {
+	existing := createUser(t, adapter)
+	existingKey := createKey(t, adapter, existing.ID)
+
+	user := newUser()
+	key := newKey(user.ID)
+	key.ID = existingKey.ID
+	expectGuamError(t, adapter.SetUser(user, &key), auth.AUTH_DUPLICATE_KEY_ID)
+
+	// The user and its key are stored together or not at all.
+	expectNoUser(t, adapter, user.ID)
+	expectKey(t, adapter, existingKey)
+}
+
+func testUpdateUser(t *testing.T, adapter auth.AdapterWithGetter) {
+	user := createUser(t, adapter)
+
+	user.Attributes["username"] = randomId()
+	err := adapter.UpdateUser(user.ID, map[string]any{
+		"username": user.Attributes["username"],
+	})
+	if err != nil {
+		t.Fatalf("UpdateUser: %v", err)
+	}
+	expectUser(t, adapter, user)
+}
+
+func testUpdateUserId(t *testing.T, adapter auth.AdapterWithGetter) {
+	user := createUser(t, adapter)
+
+	previousId := user.ID
+	user.ID = randomId()
+	if err := adapter.UpdateUser(previousId, map[string]any{"id": user.ID}); err != nil {
+		t.Fatalf("UpdateUser: %v", err)
+	}
+	expectNoUser(t, adapter, previousId)
+	expectUser(t, adapter, user)
+}
+
+func testDeleteUser(t *testing.T, adapter auth.AdapterWithGetter) {
+	user := createUser(t, adapter)
+	session := createSession(t, adapter, user.ID)
+	key := createKey(t, adapter, user.ID)
+	other := createUser(t, adapter)
+	otherSession := createSession(t, adapter, other.ID)
+	otherKey := createKey(t, adapter, other.ID)
+
+	if err := adapter.DeleteUser(user.ID); err != nil {
+		t.Fatalf("DeleteUser: %v", err)
+	}
+
+	// Deleting a user deletes its sessions and keys, and nothing else.
+	expectNoUser(t, adapter, user.ID)
+	expectNoSession(t, adapter, session.ID)
+	expectNoKey(t, adapter, key.ID)
+	expectUser(t, adapter, other)
+	expectSession(t, adapter, otherSession)
+	expectKey(t, adapter, otherKey)
+}
+
+func testDeleteUserMissing(t *testing.T, adapter auth.AdapterWithGetter) {
+	if err := adapter.DeleteUser(randomId()); err != nil {
+		t.Fatalf("DeleteUser: %v", err)
+	}
+}
+
+func testGetSessionMissing(t *testing.T, adapter SessionAdapter, _ func(t *testing.T) string) {
+	expectNoSession(t, adapter, randomId())
+}
+
+func testSetSession(t *testing.T, adapter SessionAdapter, newUserId func(t *testing.T) string) {
+	session := createSession(t, adapter, newUserId(t))
+	expectSession(t, adapter, session)
+}
+
+func testSetSessionInvalidUserId(t *testing.T, adapter auth.AdapterWithGetter) {
+	session := newSession(randomId())
+	expectGuamError(t, adapter.SetSession(session), auth.AUTH_INVALID_USER_ID)
+	expectNoSession(t, adapter, session.ID)
+}
+
+func testGetSessionsByUserId(t *testing.T, adapter SessionAdapter, newUserId func(t *testing.T) string) {
+	userId := newUserId(t)
+	first := createSession(t, adapter, userId)
+	second := createSession(t, adapter, userId)
+	createSession(t, adapter, newUserId(t))
+
+	sessions, err := adapter.GetSessionsByUserId(userId)
+	if err != nil {
+		t.Fatalf("GetSessionsByUserId: %v", err)
+	}
+	ids := sessionIds(sessions)
+	if len(sessions) != 2 || !ids[first.ID] || !ids[second.ID] {
+		t.Fatalf("expected sessions %s and %s, got %+v", first.ID, second.ID, sessions)
+	}
+	for _, session := range sessions {
+		expected := first
+		if session.ID == second.ID {
+			expected = second
+		}
+		if !equalSessions(session, expected) {
+			t.Fatalf("expected session %+v, got %+v", expected, session)
+		}
+	}
+
+	sessions, err = adapter.GetSessionsByUserId(randomId())
+	if err != nil {
+		t.Fatalf("GetSessionsByUserId: %v", err)
+	}
+	if len(sessions) != 0 {
+		t.Fatalf("expected no sessions, got %+v", sessions)
+	}
+}
+
+func testUpdateSession(t *testing.T, adapter SessionAdapter, newUserId func(t *testing.T) string) {
+	session := createSession(t, adapter, newUserId(t))
+
+	now := time.Now()
+	session.ActiveExpires = now.Add(3 * time.Hour).UnixMilli()
+	session.IdleExpires = now.Add(4 * time.Hour).UnixMilli()
+	session.Attributes["country"] = randomId()
+	err := adapter.UpdateSession(session.ID, map[string]any{
+		"active_expires": session.ActiveExpires,
+		"idle_expires":   session.IdleExpires,
+		"country":        session.Attributes["country"],
+	})
+	if err != nil {
+		t.Fatalf("UpdateSession: %v", err)
+	}
+	expectSession(t, adapter, session)
+}
+
+func testUpdateSessionId(t *testing.T, adapter SessionAdapter, newUserId func(t *testing.T) string) {
+	session := createSession(t, adapter, newUserId(t))
+
+	previousId := session.ID
+	session.ID = randomId()
+	if err := adapter.UpdateSession(previousId, map[string]any{"id": session.ID}); err != nil {
+		t.Fatalf("UpdateSession: %v", err)
+	}
+	expectNoSession(t, adapter, previousId)
+	expectSession(t, adapter, session)
+
+	sessions, err := adapter.GetSessionsByUserId(session.UserID)
+	if err != nil {
+		t.Fatalf("GetSessionsByUserId: %v", err)
+	}
+	if len(sessions) != 1 || sessions[0].ID != session.ID {
+		t.Fatalf("expected session %s, got %+v", session.ID, sessions)
+	}
+}
+
+func testDeleteSession(t *testing.T, adapter SessionAdapter, newUserId func(t *testing.T) string) {
+	userId := newUserId(t)
+	session := createSession(t, adapter, userId)
+	other := createSession(t, adapter, userId)
+
+	if err := adapter.DeleteSession(session.ID); err != nil {
+		t.Fatalf("DeleteSession: %v", err)
+	}
+	expectNoSession(t, adapter, session.ID)
+	expectSession(t, adapter, other)
+
+	if err := adapter.DeleteSession(session.ID); err != nil {
+		t.Fatalf("DeleteSession of a missing session: %v", err)
+	}
+}
+
+func testDeleteSessionsByUserId(t *testing.T, adapter SessionAdapter, newUserId func(t *testing.T) string) {
+	userId := newUserId(t)
+	first := createSession(t, adapter, userId)
+	second := createSession(t, adapter, userId)
+	otherSession := createSession(t, adapter, newUserId(t))
+
+	if err := adapter.DeleteSessionsByUserId(userId); err != nil {
+		t.Fatalf("DeleteSessionsByUserId: %v", err)
+	}
+	expectNoSession(t, adapter, first.ID)
+	expectNoSession(t, adapter, second.ID)
+	expectSession(t, adapter, otherSession)
+}
+
+func testDeleteSessionsByUserIdKeepsUser(t *testing.T, adapter auth.AdapterWithGetter) {
+	user := createUser(t, adapter)
+	createSession(t, adapter, user.ID)
+
+	if err := adapter.DeleteSessionsByUserId(user.ID); err != nil {
+		t.Fatalf("DeleteSessionsByUserId: %v", err)
+	}
+	expectUser(t, adapter, user)
+}
+
+func testGetKeyMissing(t *testing.T, adapter auth.AdapterWithGetter) {
+	expectNoKey(t, adapter, randomId())
+}
+
+func testSetKey(t *testing.T, adapter auth.AdapterWithGetter) {
+	user := createUser(t, adapter)
+	key := createKey(t, adapter, user.ID)
+	expectKey(t, adapter, key)
+}
+
+func testSetKeyNullPassword(t *testing.T, adapter auth.AdapterWithGetter) {
+	user := createUser(t, adapter)
+	key := newKey(user.ID)
+	key.HashedPassword = nil
+	if err := adapter.SetKey(key); err != nil {
+		t.Fatalf("SetKey: %v", err)
+	}
+	expectKey(t, adapter, key)
+}
+
+func testSetKeyDuplicateKeyId(t *testing.T, adapter auth.AdapterWithGetter) {
+	user := createUser(t, adapter)
+	key := createKey(t, adapter, user.ID)
+
+	duplicate := newKey(user.ID)
+	duplicate.ID = key.ID
+	expectGuamError(t, adapter.SetKey(duplicate), auth.AUTH_DUPLICATE_KEY_ID)
+	expectKey(t, adapter, key)
+}
+
+func testSetKeyInvalidUserId(t *testing.T, adapter auth.AdapterWithGetter) {
+	key := newKey(randomId())
+	expectGuamError(t, adapter.SetKey(key), auth.AUTH_INVALID_USER_ID)
+	expectNoKey(t, adapter, key.ID)
+}
+
+func testGetKeysByUserId(t *testing.T, adapter auth.AdapterWithGetter) {
+	user := createUser(t, adapter)
+	first := createKey(t, adapter, user.ID)
+	second := createKey(t, adapter, user.ID)
+	other := createUser(t, adapter)
+	createKey(t, adapter, other.ID)
+
+	keys, err := adapter.GetKeysByUserId(user.ID)
+	if err != nil {
+		t.Fatalf("GetKeysByUserId: %v", err)
+	}
+	ids := keyIds(keys)
+	if len(keys) != 2 || !ids[first.ID] || !ids[second.ID] {
+		t.Fatalf("expected keys %s and %s, got %+v", first.ID, second.ID, keys)
+	}
+
+	keys, err = adapter.GetKeysByUserId(randomId())
+	if err != nil {
+		t.Fatalf("GetKeysByUserId: %v", err)
+	}
+	if len(keys) != 0 {
+		t.Fatalf("expected no keys, got %+v", keys)
+	}
+}
+
+func testUpdateKey(t *testing.T, adapter auth.AdapterWithGetter) {
+	user := createUser(t, adapter)
+	key := createKey(t, adapter, user.ID)
+
+	hashedPassword := utils.GenerateScryptHash(randomId())
+	key.HashedPassword = &hashedPassword
+	err := adapter.UpdateKey(key.ID, map[string]any{
+		"hashed_password": hashedPassword,
+	})
+	if err != nil {
+		t.Fatalf("UpdateKey: %v", err)
+	}
+	expectKey(t, adapter, key)
+}
+
+func testUpdateKeyId(t *testing.T, adapter auth.AdapterWithGetter) {
+	user := createUser(t, adapter)
+	key := createKey(t, adapter, user.ID)
+
+	previousId := key.ID
+	key.ID = randomId()
+	if err := adapter.UpdateKey(previousId, map[string]any{"id": key.ID}); err != nil {
+		t.Fatalf("UpdateKey: %v", err)
+	}
+	expectNoKey(t, adapter, previousId)
+	expectKey(t, adapter, key)
+}
+
+func testUpdateKeyDuplicateKeyId(t *testing.T, adapter auth.AdapterWithGetter) {
+	user := createUser(t, adapter)
+	key := createKey(t, adapter, user.ID)
+	other := createKey(t, adapter, user.ID)
+
+	err := adapter.UpdateKey(key.ID, map[string]any{"id": other.ID})
+	expectGuamError(t, err, auth.AUTH_DUPLICATE_KEY_ID)
+	expectKey(t, adapter, key)
+	expectKey(t, adapter, other)
+}
+
+func testDeleteKey(t *testing.T, adapter auth.AdapterWithGetter) {
+	user := createUser(t, adapter)
+	key := createKey(t, adapter, user.ID)
+	other := createKey(t, adapter, user.ID)
+
+	if err := adapter.DeleteKey(key.ID); err != nil {
+		t.Fatalf("DeleteKey: %v", err)
+	}
+	expectNoKey(t, adapter, key.ID)
+	expectKey(t, adapter, other)
+
+	if err := adapter.DeleteKey(key.ID); err != nil {
+		t.Fatalf("DeleteKey of a missing key: %v", err)
+	}
+}
+
+func testDeleteKeysByUserId(t *testing.T, adapter auth.AdapterWithGetter) {
+	user := createUser(t, adapter)
+	first := createKey(t, adapter, user.ID)
+	second := createKey(t, adapter, user.ID)
+	other := createUser(t, adapter)
+	otherKey := createKey(t, adapter, other.ID)
+
+	if err := adapter.DeleteKeysByUserId(user.ID); err != nil {
+		t.Fatalf("DeleteKeysByUserId: %v", err)
+	}
+	expectNoKey(t, adapter, first.ID)
+	expectNoKey(t, adapter, second.ID)
+	expectKey(t, adapter, otherKey)
+	expectUser(t, adapter, user)
+}
+
+func testGetSessionAndUser(t *testing.T, adapter auth.AdapterWithGetter) {
+	user := createUser(t, adapter)
+	expected := createSession(t, adapter, user.ID)
+
+	session, joined, err := adapter.GetSessionAndUser(expected.ID)
+	if err != nil {
+		t.Fatalf("GetSessionAndUser: %v", err)
+	}
+	if session == nil || joined == nil {
+		t.Fatalf("expected session %s and user %s, got %+v and %+v", expected.ID, user.ID, session, joined)
+	}
+	if !equalSessions(*session, expected) {
+		t.Fatalf("expected session %+v, got %+v", expected, *session)
+	}
+	if joined.ID != user.ID ||
+		joined.SessionID != expected.ID ||
+		joined.Attributes["username"] != user.Attributes["username"] {
+		t.Fatalf("expected user %+v, got %+v", user, *joined)
+	}
+}
+
+func testGetSessionAndUserMissing(t *testing.T, adapter auth.AdapterWithGetter) {
+	session, user, err := adapter.GetSessionAndUser(randomId())
+	if err != nil {
+		t.Fatalf("GetSessionAndUser: %v", err)
+	}
+	if session != nil || user != nil {
+		t.Fatalf("expected no session and user, got %+v and %+v", session, user)
+	}
+}
@@ -0,0 +1,7 @@
+module github.com/seatedro/guam-adapters/adaptertest
+
+go 1.21.0
+
+require github.com/seatedro/guam v0.0.3
+
+replace github.com/seatedro/guam => ../../guam
@@ -0,0 +1,100 @@
+package composite
+
+import (
+	"github.com/seatedro/guam/auth"
+)
+
+// UserAdapter is the user and key half of auth.Adapter.
+type UserAdapter interface {
+	GetUser(userId string) (*auth.UserSchema, error)
+	SetUser(user auth.UserSchema, key *auth.KeySchema) error
+	DeleteUser(userId string) error
+	UpdateUser(userId string, partialUser map[string]any) error
+	GetKey(keyId string) (*auth.KeySchema, error)
+	GetKeysByUserId(userId string) ([]auth.KeySchema, error)
+	SetKey(key auth.KeySchema) error
+	UpdateKey(keyId string, partialKey map[string]any) error
+	DeleteKey(keyId string) error
+	DeleteKeysByUserId(userId string) error
+}
+
+// SessionAdapter is the session half of auth.Adapter.
+type SessionAdapter interface {
+	GetSession(sessionId string) (*auth.SessionSchema, error)
+	GetSessionsByUserId(userId string) ([]auth.SessionSchema, error)
+	SetSession(session auth.SessionSchema) error
+	DeleteSession(sessionId string) error
+	DeleteSessionsByUserId(userId string) error
+	UpdateSession(sessionId string, partialSession map[string]any) error
+}
+
+type compositeAdapterImpl struct {
+	UserAdapter
+	SessionAdapter
+}
+
+// CompositeAdapter returns an adapter storing users and keys with users and
+// sessions with sessions, e.g. users in Postgres and sessions in Redis.
+func CompositeAdapter(users UserAdapter, sessions SessionAdapter) auth.AdapterWithGetter {
+	return &compositeAdapterImpl{
+		UserAdapter:    users,
+		SessionAdapter: sessions,
+	}
+}
+
+// DeleteUser deletes the sessions of the user before the user itself, since
+// the session backend can't cascade deletes from the user backend.
+func (c *compositeAdapterImpl) DeleteUser(userId string) error {
+	if err := c.SessionAdapter.DeleteSessionsByUserId(userId); err != nil {
+		return err
+	}
+	return c.UserAdapter.DeleteUser(userId)
+}
+
+// SetSession checks that the user exists, since the session backend can't
+// enforce a foreign key on the user backend.
+func (c *compositeAdapterImpl) SetSession(session auth.SessionSchema) error {
+	if err := c.checkUser(session.UserID); err != nil {
+		return err
+	}
+	return c.SessionAdapter.SetSession(session)
+}
+
+// UpdateSession checks that a new user_id belongs to an existing user, like
+// SetSession.
+func (c *compositeAdapterImpl) UpdateSession(sessionId string, partialSession map[string]any) error {
+	if userId, ok := partialSession["user_id"].(string); ok {
+		if err := c.checkUser(userId); err != nil {
+			return err
+		}
+	}
+	return c.SessionAdapter.UpdateSession(sessionId, partialSession)
+}
+
+// checkUser returns AUTH_INVALID_USER_ID if there is no user with userId.
+func (c *compositeAdapterImpl) checkUser(userId string) error {
+	user, err := c.UserAdapter.GetUser(userId)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return auth.NewGuamError(auth.AUTH_INVALID_USER_ID, "")
+	}
+	return nil
+}
+
+func (c *compositeAdapterImpl) GetSessionAndUser(
+	sessionId string,
+) (*auth.SessionSchema, *auth.UserJoinSessionSchema, error) {
+	session, err := c.SessionAdapter.GetSession(sessionId)
+	if err != nil || session == nil {
+		return nil, nil, err
+	}
+
+	user, err := c.UserAdapter.GetUser(session.UserID)
+	if err != nil || user == nil {
+		return nil, nil, err
+	}
+
+	return session, &auth.UserJoinSessionSchema{UserSchema: *user, SessionID: session.ID}, nil
+}
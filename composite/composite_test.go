@@ -0,0 +1,224 @@
+package composite
+
+import (
+	"context"
+	"errors"
+	"log"
+	"maps"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/seatedro/guam-adapters/adaptertest"
+	"github.com/seatedro/guam-adapters/memory"
+	"github.com/seatedro/guam-adapters/redis"
+	"github.com/seatedro/guam/auth"
+)
+
+// users is a user adapter without keys, which the composite adapter passes
+// through untouched.
+type users struct {
+	UserAdapter
+	users map[string]auth.UserSchema
+}
+
+func (u *users) GetUser(userId string) (*auth.UserSchema, error) {
+	user, ok := u.users[userId]
+	if !ok {
+		return nil, nil
+	}
+	return &user, nil
+}
+
+func (u *users) DeleteUser(userId string) error {
+	delete(u.users, userId)
+	return nil
+}
+
+// sessions is a session adapter keeping sessions in a map. Like the redis
+// adapter, it doesn't know about users.
+type sessions struct {
+	sessions map[string]auth.SessionSchema
+}
+
+func (s *sessions) GetSession(sessionId string) (*auth.SessionSchema, error) {
+	session, ok := s.sessions[sessionId]
+	if !ok {
+		return nil, nil
+	}
+	return &session, nil
+}
+
+func (s *sessions) GetSessionsByUserId(userId string) ([]auth.SessionSchema, error) {
+	var found []auth.SessionSchema
+	for _, session := range s.sessions {
+		if session.UserID == userId {
+			found = append(found, session)
+		}
+	}
+	return found, nil
+}
+
+func (s *sessions) SetSession(session auth.SessionSchema) error {
+	session.Attributes = maps.Clone(session.Attributes)
+	s.sessions[session.ID] = session
+	return nil
+}
+
+func (s *sessions) DeleteSession(sessionId string) error {
+	delete(s.sessions, sessionId)
+	return nil
+}
+
+func (s *sessions) DeleteSessionsByUserId(userId string) error {
+	for id, session := range s.sessions {
+		if session.UserID == userId {
+			delete(s.sessions, id)
+		}
+	}
+	return nil
+}
+
+func (s *sessions) UpdateSession(sessionId string, partialSession map[string]any) error {
+	session, ok := s.sessions[sessionId]
+	if !ok {
+		return nil
+	}
+	session.Attributes = maps.Clone(session.Attributes)
+	for key, value := range partialSession {
+		switch key {
+		case "user_id":
+			session.UserID = value.(string)
+		case "active_expires":
+			session.ActiveExpires = value.(int64)
+		case "idle_expires":
+			session.IdleExpires = value.(int64)
+		default:
+			if session.Attributes == nil {
+				session.Attributes = make(map[string]any)
+			}
+			session.Attributes[key] = value
+		}
+	}
+	s.sessions[sessionId] = session
+	return nil
+}
+
+func setup() (*users, *sessions, auth.AdapterWithGetter) {
+	u := &users{users: map[string]auth.UserSchema{
+		"user": {ID: "user", Attributes: map[string]any{"username": "guam"}},
+	}}
+	s := &sessions{sessions: map[string]auth.SessionSchema{}}
+	return u, s, CompositeAdapter(u, s)
+}
+
+func TestSetSession(t *testing.T) {
+	_, s, adapter := setup()
+
+	err := adapter.SetSession(auth.SessionSchema{ID: "session", UserID: "user"})
+	if err != nil {
+		log.Fatal(err)
+	}
+	if _, ok := s.sessions["session"]; !ok {
+		log.Fatal("expected session to be stored in the session adapter")
+	}
+}
+
+func TestSetSessionInvalidUserId(t *testing.T) {
+	_, s, adapter := setup()
+
+	err := adapter.SetSession(auth.SessionSchema{ID: "session", UserID: "missing"})
+
+	var guamErr *auth.GuamError
+	if !errors.As(err, &guamErr) || guamErr.Message != auth.AUTH_INVALID_USER_ID {
+		log.Fatalf("expected %s, got %v", auth.AUTH_INVALID_USER_ID, err)
+	}
+	if len(s.sessions) != 0 {
+		log.Fatal("expected session not to be stored")
+	}
+}
+
+func TestUpdateSessionInvalidUserId(t *testing.T) {
+	_, s, adapter := setup()
+
+	if err := adapter.SetSession(auth.SessionSchema{ID: "session", UserID: "user"}); err != nil {
+		log.Fatal(err)
+	}
+	err := adapter.UpdateSession("session", map[string]any{"user_id": "missing"})
+
+	var guamErr *auth.GuamError
+	if !errors.As(err, &guamErr) || guamErr.Message != auth.AUTH_INVALID_USER_ID {
+		log.Fatalf("expected %s, got %v", auth.AUTH_INVALID_USER_ID, err)
+	}
+	if s.sessions["session"].UserID != "user" {
+		log.Fatal("expected session to keep its user")
+	}
+}
+
+func TestGetSessionAndUser(t *testing.T) {
+	_, _, adapter := setup()
+
+	if err := adapter.SetSession(auth.SessionSchema{ID: "session", UserID: "user"}); err != nil {
+		log.Fatal(err)
+	}
+
+	session, user, err := adapter.GetSessionAndUser("session")
+	if err != nil ||
+		session.ID != "session" ||
+		user.ID != "user" ||
+		user.SessionID != "session" ||
+		user.Attributes["username"] != "guam" {
+		log.Fatalf("unexpected session and user %+v %+v: %v", session, user, err)
+	}
+}
+
+func TestGetSessionAndUserMissing(t *testing.T) {
+	u, s, adapter := setup()
+
+	session, user, err := adapter.GetSessionAndUser("missing")
+	if err != nil || session != nil || user != nil {
+		log.Fatalf("expected no session and user, got %+v %+v: %v", session, user, err)
+	}
+
+	// A session whose user is gone is treated as missing.
+	s.sessions["orphan"] = auth.SessionSchema{ID: "orphan", UserID: "user"}
+	delete(u.users, "user")
+	session, user, err = adapter.GetSessionAndUser("orphan")
+	if err != nil || session != nil || user != nil {
+		log.Fatalf("expected no session and user, got %+v %+v: %v", session, user, err)
+	}
+}
+
+func TestDeleteUserDeletesSessions(t *testing.T) {
+	u, s, adapter := setup()
+
+	s.sessions["session"] = auth.SessionSchema{ID: "session", UserID: "user"}
+	s.sessions["other"] = auth.SessionSchema{ID: "other", UserID: "other"}
+
+	if err := adapter.DeleteUser("user"); err != nil {
+		log.Fatal(err)
+	}
+
+	if _, ok := u.users["user"]; ok {
+		log.Fatal("expected user to be deleted")
+	}
+	if _, ok := s.sessions["session"]; ok {
+		log.Fatal("expected the user's sessions to be deleted")
+	}
+	if _, ok := s.sessions["other"]; !ok {
+		log.Fatal("expected other sessions to be kept")
+	}
+}
+
+// TestConformance runs the suite against users in memory and sessions in an
+// in-memory Redis server.
+func TestConformance(t *testing.T) {
+	adaptertest.Run(t, func(t *testing.T) auth.AdapterWithGetter {
+		server := miniredis.RunT(t)
+		client := goredis.NewClient(&goredis.Options{Addr: server.Addr()})
+		t.Cleanup(func() { client.Close() })
+
+		sessions := redis.RedisSessionAdapter(context.Background(), client, redis.Prefixes{}, false)
+		return CompositeAdapter(memory.MemoryAdapter(), sessions)
+	})
+}
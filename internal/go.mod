@@ -0,0 +1,12 @@
+module github.com/seatedro/guam-adapters/internal
+
+go 1.21.0
+
+require (
+	github.com/seatedro/guam v0.0.3
+	go.uber.org/zap v1.26.0
+)
+
+require go.uber.org/multierr v1.10.0 // indirect
+
+replace github.com/seatedro/guam => ../../guam
@@ -0,0 +1,670 @@
+package sqlutil
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/seatedro/guam/auth"
+)
+
+// Constraint is a kind of constraint violation mapped to a guam error.
+type Constraint int
+
+const (
+	// NoConstraint is reported for errors that aren't constraint violations,
+	// or not ones mapped to guam errors.
+	NoConstraint Constraint = iota
+	// UniqueConstraint is reported for duplicate primary or unique keys.
+	UniqueConstraint
+	// ForeignKeyConstraint is reported for references to a missing row.
+	ForeignKeyConstraint
+)
+
+// Driver describes a database to Adapter: how it quotes names, what its
+// errors mean and how its values are scanned. Statements use ? placeholders.
+type Driver struct {
+	// Name prefixes the errors of the adapter, e.g. "sqlite".
+	Name    string
+	Dialect Dialect
+	// Constraint reports the constraint violated by err, if any, and a
+	// message describing the violation.
+	Constraint func(err error) (Constraint, string)
+	// UndefinedColumn reports whether err is from a query naming a column
+	// its table doesn't have. If nil, failed selects aren't retried.
+	UndefinedColumn func(err error) bool
+	// ConvertValue converts a value scanned from a column of type
+	// databaseType, as named by sql.ColumnType.DatabaseTypeName. If nil,
+	// values are decoded as scanned.
+	ConvertValue func(databaseType string, val any) (any, error)
+}
+
+// Tables names the tables of an Adapter. UserColumns, SessionColumns and
+// KeyColumns rename the columns of the guam schemas.
+type Tables struct {
+	User    string
+	Session string
+	Key     string
+
+	UserColumns    Columns
+	SessionColumns Columns
+	KeyColumns     Columns
+}
+
+// Option configures an Adapter.
+type Option func(a *Adapter)
+
+// WithLogger logs through l instead of a logger built from debugMode. A nil l
+// keeps the default logger.
+func WithLogger(l Logger) Option {
+	return func(a *Adapter) {
+		if l != nil {
+			a.logger = l
+		}
+	}
+}
+
+// Adapter is a guam adapter storing users, sessions and keys in a database
+// reached through database/sql. The SQL adapters wrap it to give WithContext
+// their own return type.
+type Adapter struct {
+	ctx                 context.Context
+	db                  *sql.DB
+	driver              Driver
+	logger              Logger
+	userHelper          func(values auth.UserSchema) ([]string, []string, []any)
+	keyHelper           func(values auth.KeySchema) ([]string, []string, []any)
+	sessionHelper       func(values auth.SessionSchema) ([]string, []string, []any)
+	tables              Tables
+	escapedUserTable    string
+	escapedKeyTable     string
+	escapedSessionTable string
+	userColumns         *ColumnSet
+	sessionColumns      *ColumnSet
+	projection          *Projection
+	selects             *selectCache
+	keyColumns          string
+}
+
+var _ auth.AdapterWithGetter = (*Adapter)(nil)
+
+// NewAdapter returns an adapter storing users, sessions and keys in db. The
+// table names in tables are part of the program's configuration, so it panics
+// if one can't be escaped.
+func NewAdapter(
+	ctx context.Context,
+	db *sql.DB,
+	driver Driver,
+	tables Tables,
+	debugMode bool,
+	opts ...Option,
+) *Adapter {
+	placeholder := func(index int) string {
+		return "?"
+	}
+	column := func(mapping Columns) func(name string) string {
+		return func(name string) string {
+			return driver.Dialect.Column(mapping, name)
+		}
+	}
+	a := &Adapter{
+		ctx:                 ctx,
+		db:                  db,
+		driver:              driver,
+		tables:              tables,
+		userHelper:          InsertHelper[auth.UserSchema](placeholder, column(tables.UserColumns)),
+		keyHelper:           InsertHelper[auth.KeySchema](placeholder, column(tables.KeyColumns)),
+		sessionHelper:       InsertHelper[auth.SessionSchema](placeholder, column(tables.SessionColumns)),
+		escapedUserTable:    driver.Dialect.MustEscapeName(tables.User),
+		escapedKeyTable:     driver.Dialect.MustEscapeName(tables.Key),
+		escapedSessionTable: driver.Dialect.MustEscapeName(tables.Session),
+		userColumns:         &ColumnSet{},
+		sessionColumns:      &ColumnSet{},
+		selects:             &selectCache{},
+	}
+	a.keyColumns = strings.Join(coreColumns[auth.KeySchema](driver.Dialect, tables.KeyColumns), ", ")
+	for _, opt := range opts {
+		opt(a)
+	}
+	if a.logger == nil {
+		a.logger = NewLogger(debugMode)
+	}
+	return a
+}
+
+// WithContext returns a copy of the adapter whose queries run with ctx.
+func (a *Adapter) WithContext(ctx context.Context) *Adapter {
+	adapter := *a
+	adapter.ctx = ctx
+	return &adapter
+}
+
+func insertIntoTable(
+	ctx context.Context,
+	tx *sql.Tx,
+	tableName string,
+	fields []string,
+	placeholders []string,
+	args []any,
+) error {
+	query := fmt.Sprintf(
+		"INSERT INTO %s ( %s ) VALUES ( %s )",
+		tableName,
+		strings.Join(fields, ", "),
+		strings.Join(placeholders, ", "),
+	)
+	_, err := tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// appendAttributes appends a column and a placeholder for every attribute.
+// Attribute names come from application code, so names that can't be escaped
+// are rejected. Attributes are sorted by name, so the same keys always give
+// the same SQL and hit the driver's statement cache.
+func (a *Adapter) appendAttributes(
+	fields []string,
+	placeholders []string,
+	args []any,
+	attributes map[string]any,
+) ([]string, []string, []any, error) {
+	keys := make([]string, 0, len(attributes))
+	for key := range attributes {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	for _, key := range keys {
+		field, err := a.driver.Dialect.EscapeIdentifier(key)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		fields = append(fields, field)
+		placeholders = append(placeholders, "?")
+		args = append(args, attributes[key])
+	}
+	return fields, placeholders, args, nil
+}
+
+// transformKeyError maps constraint violations raised while writing to the key
+// table to their guam equivalents.
+func (a *Adapter) transformKeyError(err error) error {
+	switch constraint, message := a.driver.Constraint(err); constraint {
+	case UniqueConstraint:
+		return auth.NewGuamError(auth.AUTH_DUPLICATE_KEY_ID, message)
+	case ForeignKeyConstraint:
+		return auth.NewGuamError(auth.AUTH_INVALID_USER_ID, message)
+	}
+	return err
+}
+
+// transformSessionError maps constraint violations raised while writing to the
+// session table to their guam equivalents.
+func (a *Adapter) transformSessionError(err error) error {
+	if constraint, message := a.driver.Constraint(err); constraint == ForeignKeyConstraint {
+		return auth.NewGuamError(auth.AUTH_INVALID_USER_ID, message)
+	}
+	return err
+}
+
+// transformUserError maps constraint violations raised while writing to the
+// user table to their guam equivalents. guam has no code for a duplicate user
+// id, so unique violations are returned as they are.
+func (a *Adapter) transformUserError(err error) error {
+	return a.transformSessionError(err)
+}
+
+func (a *Adapter) GetUser(
+	userId string,
+) (*auth.UserSchema, error) {
+	columns, rows, err := a.querySelect(func(s *selects) string {
+		return s.getUser
+	}, userId)
+	if err != nil {
+		a.logger.Errorln("Error while fetching User: ", err)
+		return nil, err
+	}
+	a.logger.Debugf("User: %+v\n", RedactedRows{Columns: columns, Rows: rows})
+	if len(rows) == 0 {
+		return nil, nil
+	}
+	user, err := decodeRow[auth.UserSchema](a, columns, rows[0])
+	if err != nil {
+		a.logger.Errorln("Error: ", err)
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (a *Adapter) SetUser(user auth.UserSchema, key *auth.KeySchema) error {
+	userFields, userPlaceholders, userArgs := a.userHelper(user)
+	userFields, userPlaceholders, userArgs, err := a.appendAttributes(
+		userFields,
+		userPlaceholders,
+		userArgs,
+		user.Attributes,
+	)
+	if err != nil {
+		a.logger.Errorln("Error: ", err)
+		return err
+	}
+
+	if key == nil {
+		query := fmt.Sprintf(
+			"INSERT INTO %s ( %s ) VALUES ( %s )",
+			a.escapedUserTable,
+			strings.Join(userFields, ", "),
+			strings.Join(userPlaceholders, ", "),
+		)
+
+		_, err := a.db.ExecContext(a.ctx, query, userArgs...)
+		if err != nil {
+			a.logger.Errorln("Error while inserting into DB: ", err)
+			return a.transformUserError(err)
+		}
+		a.checkColumns(a.userColumns, user.Attributes)
+		return nil
+	}
+
+	tx, err := a.db.BeginTx(a.ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	if err := insertIntoTable(a.ctx, tx, a.escapedUserTable, userFields, userPlaceholders, userArgs); err != nil {
+		a.logger.Errorln("Error while inserting into DB: ", err)
+		return a.transformUserError(err)
+	}
+
+	keyFields, keyPlaceholders, keyArgs := a.keyHelper(*key)
+
+	if err := insertIntoTable(a.ctx, tx, a.escapedKeyTable, keyFields, keyPlaceholders, keyArgs); err != nil {
+		a.logger.Errorln("Error while inserting into Keys table: ", err)
+		return a.transformKeyError(err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	a.checkColumns(a.userColumns, user.Attributes)
+	return nil
+}
+
+func (a *Adapter) DeleteUser(userId string) error {
+	query := fmt.Sprintf(
+		"DELETE FROM %s WHERE %s = ?",
+		a.escapedUserTable,
+		a.driver.Dialect.Column(a.tables.UserColumns, "id"),
+	)
+
+	_, err := a.db.ExecContext(a.ctx, query, userId)
+	if err != nil {
+		a.logger.Errorln("Error while deleting user: ", err)
+		return err
+	}
+	return nil
+}
+
+func (a *Adapter) UpdateUser(
+	userId string,
+	partialUser map[string]any,
+) error {
+	userFields, userPlaceholders, userArgs, err := a.appendAttributes(nil, nil, nil, a.tables.UserColumns.Rename(partialUser))
+	if err != nil {
+		a.logger.Errorln("Error: ", err)
+		return err
+	}
+	if len(userFields) == 0 {
+		return nil
+	}
+	query := fmt.Sprintf(
+		"UPDATE %s SET %s WHERE %s = ?",
+		a.escapedUserTable,
+		SetArgs(userFields, userPlaceholders),
+		a.driver.Dialect.Column(a.tables.UserColumns, "id"),
+	)
+
+	_, err = a.db.ExecContext(a.ctx, query, append(userArgs, userId)...)
+	if err != nil {
+		a.logger.Errorln("Error while updating user: ", err)
+		return err
+	}
+	a.checkColumns(a.userColumns, a.tables.UserColumns.Rename(partialUser))
+	return nil
+}
+
+func (a *Adapter) GetSession(
+	sessionId string,
+) (*auth.SessionSchema, error) {
+	if a.tables.Session == "" {
+		return nil, nil
+	}
+	columns, rows, err := a.querySelect(func(s *selects) string {
+		return s.getSession
+	}, sessionId)
+	if err != nil {
+		a.logger.Errorln("Error while fetching Session: ", err)
+		return nil, err
+	}
+	a.logger.Debugf("Sessions: %+v\n", RedactedRows{Columns: columns, Rows: rows})
+	if len(rows) == 0 {
+		return nil, nil
+	}
+	session, err := decodeRow[auth.SessionSchema](a, columns, rows[0])
+	if err != nil {
+		a.logger.Errorln("Error: ", err)
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (a *Adapter) GetSessionsByUserId(
+	userId string,
+) ([]auth.SessionSchema, error) {
+	if a.tables.Session == "" {
+		return nil, nil
+	}
+	columns, rows, err := a.querySelect(func(s *selects) string {
+		return s.getSessionsByUserId
+	}, userId)
+	if err != nil {
+		a.logger.Errorln("Error while fetching Sessions: ", err)
+		return nil, err
+	}
+	a.logger.Debugf("Sessions: %+v\n", RedactedRows{Columns: columns, Rows: rows})
+	if len(rows) == 0 {
+		return nil, nil
+	}
+	sessions := make([]auth.SessionSchema, len(rows))
+	for i, row := range rows {
+		sessions[i], err = decodeRow[auth.SessionSchema](a, columns, row)
+		if err != nil {
+			a.logger.Errorln("Error: ", err)
+			return nil, err
+		}
+	}
+	return sessions, nil
+}
+
+func (a *Adapter) SetSession(
+	session auth.SessionSchema,
+) error {
+	if a.tables.Session == "" {
+		return nil
+	}
+	sessionFields, sessionPlaceholders, sessionArgs := a.sessionHelper(session)
+	sessionFields, sessionPlaceholders, sessionArgs, err := a.appendAttributes(
+		sessionFields,
+		sessionPlaceholders,
+		sessionArgs,
+		session.Attributes,
+	)
+	if err != nil {
+		a.logger.Errorln("Error: ", err)
+		return err
+	}
+
+	query := fmt.Sprintf(
+		"INSERT INTO %s ( %s ) VALUES ( %s )",
+		a.escapedSessionTable,
+		strings.Join(sessionFields, ", "),
+		strings.Join(sessionPlaceholders, ", "),
+	)
+
+	_, err = a.db.ExecContext(a.ctx, query, sessionArgs...)
+	if err != nil {
+		a.logger.Errorln("Error while inserting into DB: ", err)
+		return a.transformSessionError(err)
+	}
+	a.checkColumns(a.sessionColumns, session.Attributes)
+
+	return nil
+}
+
+func (a *Adapter) DeleteSession(
+	sessionId string,
+) error {
+	if a.tables.Session == "" {
+		return nil
+	}
+	query := fmt.Sprintf(
+		"DELETE FROM %s WHERE %s = ?",
+		a.escapedSessionTable,
+		a.driver.Dialect.Column(a.tables.SessionColumns, "id"),
+	)
+
+	_, err := a.db.ExecContext(a.ctx, query, sessionId)
+	if err != nil {
+		a.logger.Errorln("Error while deleting session: ", err)
+		return err
+	}
+
+	return nil
+}
+
+func (a *Adapter) DeleteSessionsByUserId(
+	userId string,
+) error {
+	if a.tables.Session == "" {
+		return nil
+	}
+	query := fmt.Sprintf(
+		"DELETE FROM %s WHERE %s = ?",
+		a.escapedSessionTable,
+		a.driver.Dialect.Column(a.tables.SessionColumns, "user_id"),
+	)
+
+	_, err := a.db.ExecContext(a.ctx, query, userId)
+	if err != nil {
+		a.logger.Errorln("Error while deleting session: ", err)
+		return err
+	}
+
+	return nil
+}
+
+func (a *Adapter) UpdateSession(
+	sessionId string,
+	partialSession map[string]any,
+) error {
+	if a.tables.Session == "" {
+		return nil
+	}
+	sessionFields, sessionPlaceholders, sessionArgs, err := a.appendAttributes(nil, nil, nil, a.tables.SessionColumns.Rename(partialSession))
+	if err != nil {
+		a.logger.Errorln("Error: ", err)
+		return err
+	}
+	if len(sessionFields) == 0 {
+		return nil
+	}
+	query := fmt.Sprintf(
+		"UPDATE %s SET %s WHERE %s = ?",
+		a.escapedSessionTable,
+		SetArgs(sessionFields, sessionPlaceholders),
+		a.driver.Dialect.Column(a.tables.SessionColumns, "id"),
+	)
+
+	_, err = a.db.ExecContext(a.ctx, query, append(sessionArgs, sessionId)...)
+	if err != nil {
+		a.logger.Errorln("Error while updating session: ", err)
+		return a.transformSessionError(err)
+	}
+	a.checkColumns(a.sessionColumns, a.tables.SessionColumns.Rename(partialSession))
+	return nil
+}
+
+func (a *Adapter) GetKey(keyId string) (*auth.KeySchema, error) {
+	query := fmt.Sprintf(
+		"SELECT %s FROM %s WHERE %s = ?",
+		a.keyColumns,
+		a.escapedKeyTable,
+		a.driver.Dialect.Column(a.tables.KeyColumns, "id"),
+	)
+
+	keys, err := a.queryKeys(query, keyId)
+	if err != nil {
+		a.logger.Errorln("Error while fetching Key: ", err)
+		return nil, err
+	}
+	if keys != nil {
+		return &keys[0], nil
+	}
+
+	return nil, nil
+}
+
+func (a *Adapter) GetKeysByUserId(userId string) ([]auth.KeySchema, error) {
+	query := fmt.Sprintf(
+		"SELECT %s FROM %s WHERE %s = ?",
+		a.keyColumns,
+		a.escapedKeyTable,
+		a.driver.Dialect.Column(a.tables.KeyColumns, "user_id"),
+	)
+
+	keys, err := a.queryKeys(query, userId)
+	if err != nil {
+		a.logger.Errorln("Error while fetching Keys: ", err)
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+// queryKeys runs query, which selects keys, and decodes them.
+func (a *Adapter) queryKeys(query string, args ...any) ([]auth.KeySchema, error) {
+	a.logger.Debugln("Query: ", query)
+	columns, rows, err := a.queryRows(query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []auth.KeySchema
+	for _, row := range rows {
+		key, err := decodeRow[auth.KeySchema](a, columns, row)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	a.logger.Debugf("Keys: %+v\n", RedactedKeys(keys))
+	return keys, nil
+}
+
+func (a *Adapter) SetKey(key auth.KeySchema) error {
+	keyFields, keyPlaceholders, keyValues := a.keyHelper(key)
+
+	query := fmt.Sprintf(
+		"INSERT INTO %s ( %s ) VALUES ( %s )",
+		a.escapedKeyTable,
+		strings.Join(keyFields, ", "),
+		strings.Join(keyPlaceholders, ", "),
+	)
+
+	_, err := a.db.ExecContext(a.ctx, query, keyValues...)
+	if err != nil {
+		a.logger.Errorln("Error while inserting into Keys table: ", err)
+		return a.transformKeyError(err)
+	}
+
+	return nil
+}
+
+func (a *Adapter) UpdateKey(keyId string, partialKey map[string]any) error {
+	keyFields, keyPlaceholders, keyValues, err := a.appendAttributes(nil, nil, nil, a.tables.KeyColumns.Rename(partialKey))
+	if err != nil {
+		a.logger.Errorln("Error: ", err)
+		return err
+	}
+	if len(keyFields) == 0 {
+		return nil
+	}
+	query := fmt.Sprintf(
+		"UPDATE %s SET %s WHERE %s = ?",
+		a.escapedKeyTable,
+		SetArgs(keyFields, keyPlaceholders),
+		a.driver.Dialect.Column(a.tables.KeyColumns, "id"),
+	)
+
+	_, err = a.db.ExecContext(a.ctx, query, append(keyValues, keyId)...)
+	if err != nil {
+		a.logger.Errorln("Error while updating Key table: ", err)
+		return a.transformKeyError(err)
+	}
+
+	return nil
+}
+
+func (a *Adapter) DeleteKey(keyId string) error {
+	query := fmt.Sprintf(
+		"DELETE FROM %s WHERE %s = ?",
+		a.escapedKeyTable,
+		a.driver.Dialect.Column(a.tables.KeyColumns, "id"),
+	)
+
+	_, err := a.db.ExecContext(a.ctx, query, keyId)
+	if err != nil {
+		a.logger.Errorln("Error while deleteing from Key table: ", err)
+		return err
+	}
+
+	return nil
+}
+
+func (a *Adapter) DeleteKeysByUserId(userId string) error {
+	query := fmt.Sprintf(
+		"DELETE FROM %s WHERE %s = ?",
+		a.escapedKeyTable,
+		a.driver.Dialect.Column(a.tables.KeyColumns, "user_id"),
+	)
+
+	_, err := a.db.ExecContext(a.ctx, query, userId)
+	if err != nil {
+		a.logger.Errorln("Error while deleteing from Key table: ", err)
+		return err
+	}
+
+	return nil
+}
+
+func (a *Adapter) GetSessionAndUser(
+	sessionId string,
+) (*auth.SessionSchema, *auth.UserJoinSessionSchema, error) {
+	if a.tables.Session == "" {
+		return nil, nil, nil
+	}
+
+	var userColumnCount int
+	columns, rows, err := a.querySelect(func(s *selects) string {
+		userColumnCount = s.userColumnCount
+		return s.getSessionAndUser
+	}, sessionId)
+	if err != nil {
+		a.logger.Errorln("Error while fetching Session and User: ", err)
+		return nil, nil, err
+	}
+
+	a.logger.Debugf("Result: %+v\n", RedactedRows{Columns: columns, Rows: rows})
+	if len(rows) == 0 {
+		return nil, nil, nil
+	}
+
+	row := rows[0]
+	user, err := decodeRow[auth.UserSchema](a, columns[:userColumnCount], row[:userColumnCount])
+	if err != nil {
+		a.logger.Errorln("Error: ", err)
+		return nil, nil, err
+	}
+	session, err := decodeRow[auth.SessionSchema](a, columns[userColumnCount:], row[userColumnCount:])
+	if err != nil {
+		a.logger.Errorln("Error: ", err)
+		return nil, nil, err
+	}
+	return &session, &auth.UserJoinSessionSchema{UserSchema: user, SessionID: session.ID}, nil
+}
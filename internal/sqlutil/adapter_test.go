@@ -0,0 +1,74 @@
+package sqlutil
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"testing"
+)
+
+func TestWithNilLogger(t *testing.T) {
+	t.Parallel()
+
+	adapter := NewAdapter(context.Background(), nil, Driver{}, Tables{}, false, WithLogger(nil))
+	if adapter.logger == nil {
+		log.Fatal("expected the default logger to be kept")
+	}
+}
+
+func TestAppendAttributesSorted(t *testing.T) {
+	t.Parallel()
+
+	adapter := &Adapter{driver: Driver{Dialect: dialects[0]}}
+	attributes := map[string]any{"username": 1, "country": 2, "bio": 3, "email": 4}
+	for i := 0; i < 10; i++ {
+		fields, _, args, err := adapter.appendAttributes(nil, nil, nil, attributes)
+		if err != nil {
+			log.Fatal(err)
+		}
+		expected := []string{"bio", "country", "email", "username"}
+		for j, field := range fields {
+			if parts, err := parseNames(dialects[0], field); err != nil || parts[0] != expected[j] || args[j] != attributes[expected[j]] {
+				log.Fatalf("expected the attributes sorted by name, got %q and %v", fields, args)
+			}
+		}
+	}
+}
+
+// FuzzUpdateQuery checks that an attribute name can only ever end up as one
+// column in the SET clause of an update, whatever it contains.
+func FuzzUpdateQuery(f *testing.F) {
+	f.Add("username")
+	f.Add(`username" = 'admin', "id`)
+	f.Add("username` = 'admin', `id")
+	f.Add("username = ? --")
+	f.Add("a.b")
+	f.Fuzz(func(t *testing.T, attribute string) {
+		for _, dialect := range dialects {
+			adapter := &Adapter{driver: Driver{Dialect: dialect}}
+			fields, placeholders, args, err := adapter.appendAttributes(nil, nil, nil, map[string]any{
+				attribute: "value",
+			})
+			if err != nil {
+				if !errors.Is(err, ErrInvalidName) {
+					t.Fatalf("unexpected error for %q: %v", attribute, err)
+				}
+				continue
+			}
+			if len(args) != 1 || len(placeholders) != 1 || placeholders[0] != "?" {
+				t.Fatalf("unexpected placeholders %q and args %v", placeholders, args)
+			}
+
+			set := SetArgs(fields, placeholders)
+			column, ok := strings.CutSuffix(set, " = ?")
+			if !ok {
+				t.Fatalf("unexpected SET clause %s", set)
+			}
+			parts, err := parseNames(dialect, column)
+			if err != nil || len(parts) != 1 || parts[0] != attribute {
+				t.Fatalf("%q became %s in the SET clause: %v", attribute, set, err)
+			}
+		}
+	})
+}
@@ -0,0 +1,132 @@
+package sqlutil
+
+import (
+	"slices"
+	"sync"
+)
+
+// ColumnSet holds the column names of a table. Adapters share it between the
+// copies made by WithContext, so the columns are only loaded once, until they
+// are reset.
+type ColumnSet struct {
+	mu       sync.Mutex
+	columns  map[string]bool
+	declared bool
+	// resets counts the calls to Reset, so that a load started before one
+	// isn't kept.
+	resets int
+}
+
+// NewColumnSet returns a set of known columns, which are never loaded.
+func NewColumnSet(columns []string) *ColumnSet {
+	set := &ColumnSet{columns: make(map[string]bool, len(columns)), declared: true}
+	for _, column := range columns {
+		set.columns[column] = true
+	}
+	return set
+}
+
+// Get returns the columns, loading them with load on first use. A table
+// without columns, e.g. one that doesn't exist yet, is not remembered.
+//
+// load runs without holding the lock, since it may need a connection whose
+// setup reads the columns too, as Prepare does in a pool's AfterConnect.
+// Concurrent first calls may each load the columns.
+func (c *ColumnSet) Get(load func() ([]string, error)) (map[string]bool, error) {
+	c.mu.Lock()
+	columns, resets := c.columns, c.resets
+	c.mu.Unlock()
+
+	if columns != nil {
+		return columns, nil
+	}
+	loaded, err := load()
+	if err != nil {
+		return nil, err
+	}
+	columns = make(map[string]bool, len(loaded))
+	for _, column := range loaded {
+		columns[column] = true
+	}
+	if len(columns) > 0 {
+		c.mu.Lock()
+		if c.columns == nil && c.resets == resets {
+			c.columns = columns
+		}
+		c.mu.Unlock()
+	}
+	return columns, nil
+}
+
+// Declared returns the columns of a set made by NewColumnSet, sorted, and nil
+// for columns loaded from the database.
+func (c *ColumnSet) Declared() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.declared {
+		return nil
+	}
+	columns := make([]string, 0, len(c.columns))
+	for column := range c.columns {
+		columns = append(columns, column)
+	}
+	slices.Sort(columns)
+	return columns
+}
+
+// Reset forgets the loaded columns, so that the next Get loads them again, and
+// reports whether it did. Declared columns are never forgotten.
+func (c *ColumnSet) Reset() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.declared {
+		return false
+	}
+	c.columns = nil
+	c.resets++
+	return true
+}
+
+// Missing reports whether the loaded columns lack any of columns, which means
+// they were added since. It is false for declared columns, and for columns
+// not loaded yet.
+func (c *ColumnSet) Missing(columns []string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.declared || c.columns == nil {
+		return false
+	}
+	for _, column := range columns {
+		if !c.columns[column] {
+			return true
+		}
+	}
+	return false
+}
+
+// Columns maps the columns of a guam schema, named after their db tag, to the
+// columns storing them. Columns missing from the map keep their name.
+type Columns map[string]string
+
+// Column returns the column storing the field tagged name.
+func (c Columns) Column(name string) string {
+	if column, ok := c[name]; ok {
+		return column
+	}
+	return name
+}
+
+// Rename returns values keyed by the columns storing them.
+func (c Columns) Rename(values map[string]any) map[string]any {
+	if len(c) == 0 || len(values) == 0 {
+		return values
+	}
+	renamed := make(map[string]any, len(values))
+	for key, val := range values {
+		renamed[c.Column(key)] = val
+	}
+	return renamed
+}
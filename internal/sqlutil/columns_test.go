@@ -0,0 +1,100 @@
+package sqlutil
+
+import (
+	"log"
+	"testing"
+)
+
+func TestColumnSet(t *testing.T) {
+	t.Parallel()
+
+	loads := 0
+	var found []string
+	set := &ColumnSet{}
+	load := func() ([]string, error) {
+		loads++
+		return found, nil
+	}
+
+	// A table without columns is looked up again.
+	if columns, err := set.Get(load); err != nil || len(columns) != 0 {
+		log.Fatalf("expected no columns, got %v: %v", columns, err)
+	}
+	found = []string{"id", "username"}
+	if columns, err := set.Get(load); err != nil || !columns["username"] {
+		log.Fatalf("expected the username column, got %v: %v", columns, err)
+	}
+	if _, err := set.Get(load); err != nil || loads != 2 {
+		log.Fatalf("expected the columns to be loaded twice, got %d: %v", loads, err)
+	}
+
+	declared := NewColumnSet([]string{"id"})
+	if columns, err := declared.Get(load); err != nil || len(columns) != 1 || loads != 2 {
+		log.Fatalf("expected the declared columns, got %v: %v", columns, err)
+	}
+}
+
+func TestColumnSetReset(t *testing.T) {
+	t.Parallel()
+
+	found := []string{"id", "username"}
+	set := &ColumnSet{}
+	load := func() ([]string, error) {
+		return found, nil
+	}
+
+	if set.Missing([]string{"email"}) {
+		log.Fatal("expected columns not loaded yet to miss nothing")
+	}
+	if _, err := set.Get(load); err != nil {
+		log.Fatal(err)
+	}
+	if set.Missing([]string{"username"}) || !set.Missing([]string{"username", "email"}) {
+		log.Fatal("expected only the email column to be missing")
+	}
+
+	// A column added and another dropped are seen once the set is reset.
+	found = []string{"id", "email"}
+	if !set.Reset() {
+		log.Fatal("expected the loaded columns to be forgotten")
+	}
+	if columns, err := set.Get(load); err != nil || !columns["email"] || columns["username"] {
+		log.Fatalf("expected the columns to be loaded again, got %v: %v", columns, err)
+	}
+
+	if set.Declared() != nil {
+		log.Fatal("expected loaded columns not to be declared")
+	}
+	declared := NewColumnSet([]string{"username", "id"})
+	if columns := declared.Declared(); len(columns) != 2 || columns[0] != "id" || columns[1] != "username" {
+		log.Fatalf("expected the declared columns, got %v", columns)
+	}
+	if declared.Reset() || declared.Missing([]string{"email"}) {
+		log.Fatal("expected the declared columns to be kept")
+	}
+	if columns, err := declared.Get(load); err != nil || len(columns) != 2 {
+		log.Fatalf("expected the declared columns, got %v: %v", columns, err)
+	}
+}
+
+func TestColumnSetLoadsUnlocked(t *testing.T) {
+	t.Parallel()
+
+	set := &ColumnSet{}
+
+	// load may read the set itself, and a reset while it runs drops what it
+	// loaded.
+	columns, err := set.Get(func() ([]string, error) {
+		if set.Missing([]string{"email"}) {
+			log.Fatal("expected columns not loaded yet to miss nothing")
+		}
+		set.Reset()
+		return []string{"id"}, nil
+	})
+	if err != nil || !columns["id"] {
+		log.Fatalf("expected the loaded columns, got %v: %v", columns, err)
+	}
+	if columns, err := set.Get(func() ([]string, error) { return []string{"email"}, nil }); err != nil || !columns["email"] {
+		log.Fatalf("expected the columns to be loaded again, got %v: %v", columns, err)
+	}
+}
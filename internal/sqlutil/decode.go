@@ -0,0 +1,110 @@
+package sqlutil
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// DecodeRow sets the fields of a T from the columns named by their db tag and
+// collects every other column into its Attributes field. If jsonAttributes is
+// set, Attributes is read from that column instead.
+func DecodeRow[T any](columns []string, values []any, jsonAttributes string) (T, error) {
+	var row T
+	v := reflect.ValueOf(&row).Elem()
+	t := v.Type()
+
+	attributesField := -1
+	fields := make(map[string]int, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Name == "Attributes" {
+			attributesField = i
+			continue
+		}
+		tag, _, _ := ParseTag(field.Tag.Get("db"))
+		if tag == "" {
+			continue
+		}
+		fields[tag] = i
+	}
+
+	attributes := make(map[string]any)
+	for i, column := range columns {
+		if index, ok := fields[column]; ok {
+			if err := assignValue(v.Field(index), values[i]); err != nil {
+				return row, fmt.Errorf("column %s: %w", column, err)
+			}
+			continue
+		}
+		switch jsonAttributes {
+		case "":
+			attributes[column] = values[i]
+		case column:
+			if stored, ok := values[i].(map[string]any); ok {
+				attributes = stored
+			}
+		}
+	}
+
+	if attributesField >= 0 {
+		v.Field(attributesField).Set(reflect.ValueOf(attributes))
+	}
+	return row, nil
+}
+
+// SchemaColumns returns the columns declared by the db tags of T, a guam
+// schema, in the order of its fields. Attributes isn't a column.
+func SchemaColumns[T any]() []string {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+
+	var columns []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Name == "Attributes" {
+			continue
+		}
+		if tag, _, _ := ParseTag(field.Tag.Get("db")); tag != "" {
+			columns = append(columns, tag)
+		}
+	}
+	return columns
+}
+
+// assignValue sets field to val, converting between numeric types.
+func assignValue(field reflect.Value, val any) error {
+	if val == nil {
+		field.Set(reflect.Zero(field.Type()))
+		return nil
+	}
+	if field.Kind() == reflect.Pointer {
+		ptr := reflect.New(field.Type().Elem())
+		if err := assignValue(ptr.Elem(), val); err != nil {
+			return err
+		}
+		field.Set(ptr)
+		return nil
+	}
+
+	rv := reflect.ValueOf(val)
+	switch {
+	case rv.Type().AssignableTo(field.Type()):
+		field.Set(rv)
+	case isNumber(rv.Kind()) && isNumber(field.Kind()):
+		field.Set(rv.Convert(field.Type()))
+	case field.Kind() == reflect.String && rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() == reflect.Uint8:
+		field.SetString(string(rv.Bytes()))
+	default:
+		return fmt.Errorf("cannot assign %T to %s", val, field.Type())
+	}
+	return nil
+}
+
+func isNumber(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
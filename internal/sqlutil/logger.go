@@ -0,0 +1,108 @@
+package sqlutil
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/seatedro/guam/auth"
+	"go.uber.org/zap"
+)
+
+// Redacted replaces secrets in debug dumps.
+const Redacted = "[REDACTED]"
+
+// secretColumns are the columns whose values are never logged.
+var secretColumns = map[string]bool{"hashed_password": true}
+
+// Logger is the subset of *zap.SugaredLogger used by the adapters.
+type Logger interface {
+	Debugln(args ...any)
+	Debugf(template string, args ...any)
+	Errorln(args ...any)
+}
+
+// NewLogger returns the logger used when none is given: a development logger
+// in debug mode, and one logging errors only otherwise. It is owned by the
+// adapter and never installed as zap's global logger.
+func NewLogger(debugMode bool) Logger {
+	var (
+		l   *zap.Logger
+		err error
+	)
+	if debugMode {
+		l, err = zap.NewDevelopment()
+	} else {
+		l, err = zap.NewProduction(zap.IncreaseLevel(zap.ErrorLevel))
+	}
+	if err != nil {
+		return zap.NewNop().Sugar()
+	}
+	return l.Sugar()
+}
+
+// SlogLogger formats messages like zap's sugared logger, only once slog has
+// accepted their level.
+type SlogLogger struct {
+	L *slog.Logger
+}
+
+func (s SlogLogger) Debugln(args ...any) {
+	if s.L.Enabled(context.Background(), slog.LevelDebug) {
+		s.L.Debug(sprintln(args...))
+	}
+}
+
+func (s SlogLogger) Debugf(template string, args ...any) {
+	if s.L.Enabled(context.Background(), slog.LevelDebug) {
+		s.L.Debug(strings.TrimSuffix(fmt.Sprintf(template, args...), "\n"))
+	}
+}
+
+func (s SlogLogger) Errorln(args ...any) {
+	if s.L.Enabled(context.Background(), slog.LevelError) {
+		s.L.Error(sprintln(args...))
+	}
+}
+
+func sprintln(args ...any) string {
+	return strings.TrimSuffix(fmt.Sprintln(args...), "\n")
+}
+
+// RedactedRows formats query results with the values of secret columns
+// redacted. It is only formatted if the message is logged.
+type RedactedRows struct {
+	Columns []string
+	Rows    [][]any
+}
+
+func (r RedactedRows) String() string {
+	rows := make([][]any, len(r.Rows))
+	for i, row := range r.Rows {
+		rows[i] = make([]any, len(row))
+		for j, val := range row {
+			if j < len(r.Columns) && secretColumns[r.Columns[j]] && val != nil {
+				val = Redacted
+			}
+			rows[i][j] = val
+		}
+	}
+	return fmt.Sprintf("%+v", rows)
+}
+
+// RedactedKeys formats keys with their hashed passwords redacted. It is only
+// formatted if the message is logged.
+type RedactedKeys []auth.KeySchema
+
+func (k RedactedKeys) String() string {
+	keys := make([]string, len(k))
+	for i, key := range k {
+		hashedPassword := "<nil>"
+		if key.HashedPassword != nil {
+			hashedPassword = Redacted
+		}
+		keys[i] = fmt.Sprintf("{ID:%s UserID:%s HashedPassword:%s}", key.ID, key.UserID, hashedPassword)
+	}
+	return "[" + strings.Join(keys, " ") + "]"
+}
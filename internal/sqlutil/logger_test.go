@@ -0,0 +1,33 @@
+package sqlutil
+
+import (
+	"log"
+	"strings"
+	"testing"
+
+	"github.com/seatedro/guam/auth"
+)
+
+func TestRedactedDumps(t *testing.T) {
+	t.Parallel()
+
+	hashedPassword := "s3cret-hash"
+	keys := RedactedKeys{{ID: "key", UserID: "user", HashedPassword: &hashedPassword}}
+	if dump := keys.String(); strings.Contains(dump, hashedPassword) || !strings.Contains(dump, Redacted) {
+		log.Fatalf("expected the hashed password to be redacted, got %s", dump)
+	}
+	if dump := keys.String(); !strings.Contains(dump, "ID:key UserID:user") {
+		log.Fatalf("expected the other fields to be logged, got %s", dump)
+	}
+	if dump := (RedactedKeys{auth.KeySchema{ID: "key"}}).String(); !strings.Contains(dump, "HashedPassword:<nil>") {
+		log.Fatalf("expected a missing password to be logged as such, got %s", dump)
+	}
+
+	rows := RedactedRows{
+		Columns: []string{"id", "hashed_password"},
+		Rows:    [][]any{{"user", hashedPassword}},
+	}
+	if dump := rows.String(); strings.Contains(dump, hashedPassword) || !strings.Contains(dump, "user") {
+		log.Fatalf("expected only the hashed password to be redacted, got %s", dump)
+	}
+}
@@ -0,0 +1,99 @@
+// Package sqlutil holds the code shared by the SQL adapters: escaping names,
+// building inserts from struct tags, decoding rows and logging. Adapter is the
+// whole adapter for databases reached through database/sql.
+package sqlutil
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrInvalidName is returned when a table or column name can't be escaped.
+var ErrInvalidName = errors.New("invalid name")
+
+// Dialect describes how a database quotes identifiers.
+type Dialect struct {
+	// EscapeChar quotes identifiers; it is doubled inside them.
+	EscapeChar string
+	// MaxIdentifierLength is the longest identifier the database accepts, in
+	// bytes, or 0 if there's no limit.
+	MaxIdentifierLength int
+}
+
+// EscapeName escapes a table name, which may be schema-qualified. Each part of
+// "schema.table" is quoted separately and embedded quote characters are
+// doubled, so the result always names exactly one table. Names with an empty
+// part, a NUL character, invalid UTF-8 or a part longer than the database
+// allows are rejected with an error wrapping ErrInvalidName.
+func (d Dialect) EscapeName(val string) (string, error) {
+	parts := strings.Split(val, ".")
+	for i, part := range parts {
+		if err := d.validateIdentifier(part); err != nil {
+			return "", err
+		}
+		parts[i] = d.QuoteIdentifier(part)
+	}
+	return strings.Join(parts, "."), nil
+}
+
+// EscapeIdentifier escapes a single identifier, such as a column name. Unlike
+// EscapeName, dots are part of the name.
+func (d Dialect) EscapeIdentifier(val string) (string, error) {
+	if err := d.validateIdentifier(val); err != nil {
+		return "", err
+	}
+	return d.QuoteIdentifier(val), nil
+}
+
+// MustEscapeName escapes a table name from an adapter's configuration, where
+// an invalid name is a programming error, so it panics. An empty name, used
+// for an optional table, stays empty.
+func (d Dialect) MustEscapeName(val string) string {
+	if val == "" {
+		return ""
+	}
+	escaped, err := d.EscapeName(val)
+	if err != nil {
+		panic(err)
+	}
+	return escaped
+}
+
+// QuoteIdentifier quotes val without validating it. It is meant for names
+// known to be valid, like those of the guam schema.
+func (d Dialect) QuoteIdentifier(val string) string {
+	return d.EscapeChar + strings.ReplaceAll(val, d.EscapeChar, d.EscapeChar+d.EscapeChar) + d.EscapeChar
+}
+
+// Column returns the escaped column storing the field tagged name, in a table
+// whose columns are renamed by mapping.
+func (d Dialect) Column(mapping Columns, name string) string {
+	return d.QuoteIdentifier(mapping.Column(name))
+}
+
+// SelectColumn returns the escaped column storing the field tagged name, as
+// read by a select: renamed columns are aliased back to name, so that rows
+// decode as if the table used the guam names.
+func (d Dialect) SelectColumn(mapping Columns, name string) string {
+	column := mapping.Column(name)
+	if column == name {
+		return d.QuoteIdentifier(name)
+	}
+	return d.QuoteIdentifier(column) + " AS " + d.QuoteIdentifier(name)
+}
+
+func (d Dialect) validateIdentifier(val string) error {
+	switch {
+	case val == "":
+		return fmt.Errorf("%w: empty identifier", ErrInvalidName)
+	case !utf8.ValidString(val):
+		return fmt.Errorf("%w %q: not valid UTF-8", ErrInvalidName, val)
+	case strings.ContainsRune(val, 0):
+		return fmt.Errorf("%w %q: contains a NUL character", ErrInvalidName, val)
+	case d.MaxIdentifierLength > 0 && len(val) > d.MaxIdentifierLength:
+		return fmt.Errorf("%w %q: longer than %d bytes", ErrInvalidName, val, d.MaxIdentifierLength)
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+package sqlutil
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"testing"
+)
+
+// dialects are the escaping rules of the adapters sharing this package.
+var dialects = []Dialect{
+	{EscapeChar: `"`},
+	{EscapeChar: "`", MaxIdentifierLength: 64},
+}
+
+// parseNames parses a dot-separated list of quoted identifiers, as produced
+// by EscapeName, and returns the unquoted parts. It fails if anything is left
+// outside the quotes.
+func parseNames(d Dialect, escaped string) ([]string, error) {
+	quote := d.EscapeChar
+	var parts []string
+	for {
+		if !strings.HasPrefix(escaped, quote) {
+			return nil, fmt.Errorf("expected a quote at %q", escaped)
+		}
+		escaped = escaped[len(quote):]
+
+		var part strings.Builder
+		for {
+			i := strings.Index(escaped, quote)
+			if i < 0 {
+				return nil, fmt.Errorf("unterminated identifier")
+			}
+			part.WriteString(escaped[:i])
+			escaped = escaped[i+len(quote):]
+			if !strings.HasPrefix(escaped, quote) {
+				break
+			}
+			part.WriteString(quote)
+			escaped = escaped[len(quote):]
+		}
+		parts = append(parts, part.String())
+
+		if escaped == "" {
+			return parts, nil
+		}
+		if !strings.HasPrefix(escaped, ".") {
+			return nil, fmt.Errorf("unexpected %q after identifier", escaped)
+		}
+		escaped = escaped[1:]
+	}
+}
+
+func TestEscapeName(t *testing.T) {
+	t.Parallel()
+
+	dialect := Dialect{EscapeChar: `"`}
+	valid := map[string]string{
+		"auth_user":        `"auth_user"`,
+		"public.auth_user": `"public"."auth_user"`,
+		`a"b`:              `"a""b"`,
+		"AuthUser":         `"AuthUser"`,
+	}
+	for name, expected := range valid {
+		escaped, err := dialect.EscapeName(name)
+		if err != nil || escaped != expected {
+			log.Fatalf("expected %s for %q, got %s: %v", expected, name, escaped, err)
+		}
+	}
+
+	invalid := []string{"", ".auth_user", "public.", "a..b", "a\x00b", "\xff"}
+	for _, d := range dialects {
+		for _, name := range invalid {
+			if _, err := d.EscapeName(name); !errors.Is(err, ErrInvalidName) {
+				log.Fatalf("expected %v for %q, got %v", ErrInvalidName, name, err)
+			}
+		}
+	}
+}
+
+func TestEscapeIdentifierKeepsDots(t *testing.T) {
+	t.Parallel()
+
+	escaped, err := Dialect{EscapeChar: `"`}.EscapeIdentifier("a.b")
+	if err != nil || escaped != `"a.b"` {
+		log.Fatalf(`expected "a.b", got %s: %v`, escaped, err)
+	}
+}
+
+func TestColumn(t *testing.T) {
+	t.Parallel()
+
+	dialect := Dialect{EscapeChar: `"`}
+	mapping := Columns{"user_id": "account_id"}
+	if column := dialect.Column(mapping, "user_id"); column != `"account_id"` {
+		log.Fatalf(`expected "account_id", got %s`, column)
+	}
+	if column := dialect.SelectColumn(mapping, "user_id"); column != `"account_id" AS "user_id"` {
+		log.Fatalf(`expected "account_id" AS "user_id", got %s`, column)
+	}
+	if column := dialect.SelectColumn(mapping, "id"); column != `"id"` {
+		log.Fatalf(`expected "id", got %s`, column)
+	}
+}
+
+func FuzzEscapeName(f *testing.F) {
+	f.Add("auth_user")
+	f.Add("public.auth_user")
+	f.Add(`a"b`)
+	f.Add("a`b")
+	f.Add(`auth_user"; DROP TABLE auth_user; --`)
+	f.Add(`"public"."auth_user"`)
+	f.Fuzz(func(t *testing.T, name string) {
+		for _, dialect := range dialects {
+			escaped, err := dialect.EscapeName(name)
+			if err != nil {
+				if !errors.Is(err, ErrInvalidName) {
+					t.Fatalf("unexpected error for %q: %v", name, err)
+				}
+				continue
+			}
+			parts, err := parseNames(dialect, escaped)
+			if err != nil {
+				t.Fatalf("%q escaped to %s, which doesn't parse: %v", name, escaped, err)
+			}
+			if strings.Join(parts, ".") != name {
+				t.Fatalf("%q escaped to %s, which names %q", name, escaped, parts)
+			}
+		}
+	})
+}
+
+func TestDialect(t *testing.T) {
+	t.Parallel()
+
+	backtick := Dialect{EscapeChar: "`", MaxIdentifierLength: 64}
+	if escaped, err := backtick.EscapeName("auth.a`b"); err != nil || escaped != "`auth`.`a``b`" {
+		log.Fatalf("expected `auth`.`a``b`, got %s: %v", escaped, err)
+	}
+	if _, err := backtick.EscapeIdentifier(strings.Repeat("a", 65)); !errors.Is(err, ErrInvalidName) {
+		log.Fatalf("expected %v, got %v", ErrInvalidName, err)
+	}
+
+	// Without a maximum length, any length is accepted.
+	unlimited := Dialect{EscapeChar: `"`}
+	if _, err := unlimited.EscapeIdentifier(strings.Repeat("a", 1000)); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func TestMustEscapeName(t *testing.T) {
+	t.Parallel()
+
+	dialect := Dialect{EscapeChar: `"`}
+	if escaped := dialect.MustEscapeName(""); escaped != "" {
+		log.Fatalf("expected an empty name to stay empty, got %s", escaped)
+	}
+
+	defer func() {
+		if err, _ := recover().(error); !errors.Is(err, ErrInvalidName) {
+			log.Fatalf("expected a panic with %v, got %v", ErrInvalidName, err)
+		}
+	}()
+	dialect.MustEscapeName("auth..user")
+}
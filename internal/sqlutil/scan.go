@@ -0,0 +1,53 @@
+package sqlutil
+
+import "fmt"
+
+// queryRows runs query and returns the names of its columns and the values of
+// every row, converted by the driver's ConvertValue.
+func (a *Adapter) queryRows(query string, args ...any) ([]string, [][]any, error) {
+	rows, err := a.db.QueryContext(a.ctx, query, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, nil, err
+	}
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var values [][]any
+	for rows.Next() {
+		row := make([]any, len(columns))
+		dest := make([]any, len(columns))
+		for i := range row {
+			dest[i] = &row[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, nil, err
+		}
+		if a.driver.ConvertValue != nil {
+			for i, val := range row {
+				if row[i], err = a.driver.ConvertValue(types[i].DatabaseTypeName(), val); err != nil {
+					return nil, nil, err
+				}
+			}
+		}
+		values = append(values, row)
+	}
+	return columns, values, rows.Err()
+}
+
+// decodeRow sets the fields of a T from the columns named by their db tag and
+// collects every other column into its Attributes field.
+func decodeRow[T any](a *Adapter, columns []string, values []any) (T, error) {
+	row, err := DecodeRow[T](columns, values, "")
+	if err != nil {
+		return row, fmt.Errorf("%s: %w", a.driver.Name, err)
+	}
+	return row, nil
+}
@@ -0,0 +1,308 @@
+package sqlutil
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/seatedro/guam/auth"
+)
+
+// Projection lists the attributes read by GetSessionAndUser, on top of the
+// guam columns, which are always read.
+type Projection struct {
+	UserAttributes    []string
+	SessionAttributes []string
+}
+
+// WithSessionAndUserProjection limits GetSessionAndUser to the attributes of
+// projection. The attribute names, like table names, are part of the
+// program's configuration, so it panics if d can't escape one.
+func WithSessionAndUserProjection(d Dialect, projection Projection) Option {
+	projection.UserAttributes = d.mustValidateIdentifiers(projection.UserAttributes)
+	projection.SessionAttributes = d.mustValidateIdentifiers(projection.SessionAttributes)
+	return func(a *Adapter) {
+		a.projection = &projection
+	}
+}
+
+// mustValidateIdentifiers returns a copy of names, which the caller may
+// change once the option is built, after checking that each can be escaped.
+func (d Dialect) mustValidateIdentifiers(names []string) []string {
+	for _, name := range names {
+		if err := d.validateIdentifier(name); err != nil {
+			panic(err)
+		}
+	}
+	return slices.Clone(names)
+}
+
+// WithUserColumns declares the columns of the user table, which getters read.
+// Without it, they are read from the database the first time they're needed,
+// and again after RefreshColumns.
+func WithUserColumns(columns []string) Option {
+	return func(a *Adapter) {
+		a.userColumns = NewColumnSet(columns)
+	}
+}
+
+// WithSessionColumns declares the columns of the session table, which getters
+// read. Without it, they are read from the database the first time they're
+// needed, and again after RefreshColumns.
+func WithSessionColumns(columns []string) Option {
+	return func(a *Adapter) {
+		a.sessionColumns = NewColumnSet(columns)
+	}
+}
+
+// selects holds the queries reading users and sessions. They list their
+// columns, which include the attribute columns of the tables; unless those
+// are declared with WithUserColumns and WithSessionColumns, they are read
+// from the database, so the queries are built on first use.
+type selects struct {
+	getUser             string
+	getSession          string
+	getSessionsByUserId string
+	getSessionAndUser   string
+	// userColumnCount is the number of user columns read by
+	// getSessionAndUser, which come before the session columns; both tables
+	// have an id column, and attributes can have any name, so the row is
+	// split by position.
+	userColumnCount int
+}
+
+// selectCache holds the selects once built. It is shared by the copies made
+// by WithContext.
+type selectCache struct {
+	mu      sync.Mutex
+	selects *selects
+}
+
+// loadSelects returns the queries reading users and sessions, building them
+// on first use. They aren't kept if a table has no columns yet.
+func (a *Adapter) loadSelects() (*selects, error) {
+	a.selects.mu.Lock()
+	defer a.selects.mu.Unlock()
+
+	if a.selects.selects != nil {
+		return a.selects.selects, nil
+	}
+
+	d := a.driver.Dialect
+	userColumns, complete, err := selectColumns[auth.UserSchema](a, a.escapedUserTable, a.tables.UserColumns, a.userColumns)
+	if err != nil {
+		return nil, err
+	}
+	userId := d.Column(a.tables.UserColumns, "id")
+	s := &selects{
+		getUser: fmt.Sprintf(
+			"SELECT %s FROM %s WHERE %s = ?",
+			strings.Join(userColumns, ", "),
+			a.escapedUserTable,
+			userId,
+		),
+	}
+	if a.tables.Session == "" {
+		if complete {
+			a.selects.selects = s
+		}
+		return s, nil
+	}
+
+	sessionColumns, sessionComplete, err := selectColumns[auth.SessionSchema](
+		a,
+		a.escapedSessionTable,
+		a.tables.SessionColumns,
+		a.sessionColumns,
+	)
+	if err != nil {
+		return nil, err
+	}
+	complete = complete && sessionComplete
+	sessionId := d.Column(a.tables.SessionColumns, "id")
+	sessionUserId := d.Column(a.tables.SessionColumns, "user_id")
+	s.getSession = fmt.Sprintf(
+		"SELECT %s FROM %s WHERE %s = ?",
+		strings.Join(sessionColumns, ", "),
+		a.escapedSessionTable,
+		sessionId,
+	)
+	s.getSessionsByUserId = fmt.Sprintf(
+		"SELECT %s FROM %s WHERE %s = ?",
+		strings.Join(sessionColumns, ", "),
+		a.escapedSessionTable,
+		sessionUserId,
+	)
+
+	if a.projection != nil {
+		userColumns = projectColumns[auth.UserSchema](d, a.tables.UserColumns, a.projection.UserAttributes)
+		sessionColumns = projectColumns[auth.SessionSchema](d, a.tables.SessionColumns, a.projection.SessionAttributes)
+	}
+	s.getSessionAndUser = fmt.Sprintf(
+		"SELECT %[3]s, %[4]s "+
+			"FROM %[2]s INNER JOIN %[1]s ON %[1]s.%[5]s = %[2]s.%[6]s WHERE %[2]s.%[7]s = ?",
+		a.escapedUserTable,
+		a.escapedSessionTable,
+		qualifyColumns(a.escapedUserTable, userColumns),
+		qualifyColumns(a.escapedSessionTable, sessionColumns),
+		userId,
+		sessionUserId,
+		sessionId,
+	)
+	s.userColumnCount = len(userColumns)
+
+	if complete {
+		a.selects.selects = s
+	}
+	return s, nil
+}
+
+// RefreshColumns forgets the columns read from the database, so that the
+// next getters read them again: columns added since are read back, and
+// dropped ones are no longer selected. Declared columns are kept.
+//
+// Columns are also read again when a select fails on a dropped column, and
+// when attributes are written to a column added since.
+func (a *Adapter) RefreshColumns() {
+	a.refreshColumns()
+}
+
+// refreshColumns forgets the columns read from the database and the selects
+// built from them, and reports whether there were any.
+func (a *Adapter) refreshColumns() bool {
+	a.selects.mu.Lock()
+	defer a.selects.mu.Unlock()
+
+	a.selects.selects = nil
+	user := a.userColumns.Reset()
+	session := a.sessionColumns.Reset()
+	return user || session
+}
+
+// checkColumns refreshes the columns once attributes were written to columns
+// of the table that weren't there when they were read.
+func (a *Adapter) checkColumns(columns *ColumnSet, attributes map[string]any) {
+	if len(attributes) == 0 {
+		return
+	}
+	names := make([]string, 0, len(attributes))
+	for name := range attributes {
+		names = append(names, name)
+	}
+	if columns.Missing(names) {
+		a.logger.Debugln("Reading columns again for: ", names)
+		a.refreshColumns()
+	}
+}
+
+// querySelect runs the select picked by query. If it fails on a column
+// dropped since the columns were read, they are read again and the select
+// runs once more.
+func (a *Adapter) querySelect(query func(s *selects) string, args ...any) ([]string, [][]any, error) {
+	for retry := true; ; retry = false {
+		selects, err := a.loadSelects()
+		if err != nil {
+			return nil, nil, err
+		}
+		q := query(selects)
+		a.logger.Debugln("Query: ", q)
+
+		columns, rows, err := a.queryRows(q, args...)
+		if err != nil && retry && a.driver.UndefinedColumn != nil && a.driver.UndefinedColumn(err) {
+			a.logger.Debugln("Reading columns again after: ", err)
+			if a.refreshColumns() {
+				continue
+			}
+		}
+		return columns, rows, err
+	}
+}
+
+// tableColumns returns the columns of table, read from an empty result.
+func (a *Adapter) tableColumns(table string) ([]string, error) {
+	query := fmt.Sprintf("SELECT * FROM %s LIMIT 0", table)
+	a.logger.Debugln("Query: ", query)
+
+	rows, err := a.db.QueryContext(a.ctx, query)
+	if err != nil {
+		a.logger.Errorln("Error while fetching columns: ", err)
+		return nil, err
+	}
+	defer rows.Close()
+	return rows.Columns()
+}
+
+// coreColumns returns the columns of the guam schema T as selected, aliased
+// to their field names if mapping renames them.
+func coreColumns[T any](d Dialect, mapping Columns) []string {
+	columns := SchemaColumns[T]()
+	for i, column := range columns {
+		columns[i] = d.SelectColumn(mapping, column)
+	}
+	return columns
+}
+
+// isCoreColumn reports whether column stores a field of the guam schema T, or
+// is named after one, so it can't be read as an attribute.
+func isCoreColumn[T any](mapping Columns, column string) bool {
+	for _, core := range SchemaColumns[T]() {
+		if core == column || mapping.Column(core) == column {
+			return true
+		}
+	}
+	return false
+}
+
+// selectColumns returns the escaped columns read from table: the guam columns
+// of T, then the attribute columns. complete is false if the columns had to
+// be read from the database and none were found.
+func selectColumns[T any](
+	a *Adapter,
+	table string,
+	mapping Columns,
+	columns *ColumnSet,
+) (selected []string, complete bool, err error) {
+	known, err := columns.Get(func() ([]string, error) {
+		return a.tableColumns(table)
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	var attributes []string
+	for column := range known {
+		if !isCoreColumn[T](mapping, column) {
+			attributes = append(attributes, column)
+		}
+	}
+	slices.Sort(attributes)
+
+	selected = coreColumns[T](a.driver.Dialect, mapping)
+	for _, attribute := range attributes {
+		selected = append(selected, a.driver.Dialect.QuoteIdentifier(attribute))
+	}
+	return selected, len(known) > 0, nil
+}
+
+// projectColumns returns the escaped columns read from a table to get the
+// guam columns of T and attributes.
+func projectColumns[T any](d Dialect, mapping Columns, attributes []string) []string {
+	columns := coreColumns[T](d, mapping)
+	for _, attribute := range attributes {
+		if !isCoreColumn[T](mapping, attribute) {
+			columns = append(columns, d.QuoteIdentifier(attribute))
+		}
+	}
+	return columns
+}
+
+// qualifyColumns prefixes the selected columns with their table; aliases
+// stay as they are.
+func qualifyColumns(table string, columns []string) string {
+	qualified := make([]string, len(columns))
+	for i, column := range columns {
+		qualified[i] = table + "." + column
+	}
+	return strings.Join(qualified, ", ")
+}
@@ -0,0 +1,82 @@
+package sqlutil
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"testing"
+)
+
+func TestSelectsWithDeclaredColumns(t *testing.T) {
+	t.Parallel()
+
+	// A nil DB proves the declared columns are used instead of the schema.
+	adapter := NewAdapter(context.Background(), nil, Driver{Dialect: Dialect{EscapeChar: `"`}}, Tables{
+		User:    "auth_user",
+		Session: "user_session",
+		Key:     "user_key",
+	}, false,
+		WithUserColumns([]string{"id", "username", "email"}),
+		WithSessionColumns([]string{"id", "user_id", "active_expires", "idle_expires", "country"}),
+		WithSessionAndUserProjection(Dialect{EscapeChar: `"`}, Projection{UserAttributes: []string{"username"}}),
+	)
+
+	selects, err := adapter.loadSelects()
+	if err != nil {
+		log.Fatal(err)
+	}
+	expected := `SELECT "id", "email", "username" FROM "auth_user" WHERE "id" = ?`
+	if selects.getUser != expected {
+		log.Fatalf("expected %s, got %s", expected, selects.getUser)
+	}
+	if !strings.Contains(selects.getSession, `"country"`) || strings.Contains(selects.getSession, "*") {
+		log.Fatalf("expected the session columns to be listed, got %s", selects.getSession)
+	}
+
+	query := selects.getSessionAndUser
+	if !strings.Contains(query, `"auth_user"."username"`) ||
+		strings.Contains(query, `"email"`) ||
+		strings.Contains(query, `"country"`) ||
+		strings.Contains(query, "*") {
+		log.Fatalf("expected the projection to be selected, got %s", query)
+	}
+}
+
+func TestSelectsWithMappedColumns(t *testing.T) {
+	t.Parallel()
+
+	adapter := NewAdapter(context.Background(), nil, Driver{Dialect: Dialect{EscapeChar: "`"}}, Tables{
+		User:           "auth_user",
+		Session:        "user_session",
+		UserColumns:    Columns{"id": "uid"},
+		SessionColumns: Columns{"user_id": "account_id"},
+	}, false,
+		WithUserColumns([]string{"uid", "username"}),
+		WithSessionColumns([]string{"id", "account_id", "active_expires", "idle_expires"}),
+	)
+
+	selects, err := adapter.loadSelects()
+	if err != nil {
+		log.Fatal(err)
+	}
+	expected := "SELECT `uid` AS `id`, `username` FROM `auth_user` WHERE `uid` = ?"
+	if selects.getUser != expected {
+		log.Fatalf("expected %s, got %s", expected, selects.getUser)
+	}
+	if !strings.Contains(selects.getSessionAndUser, "ON `auth_user`.`uid` = `user_session`.`account_id`") {
+		log.Fatalf("expected the join to use the mapped columns, got %s", selects.getSessionAndUser)
+	}
+}
+
+func TestSessionAndUserProjectionRejectsInvalidNames(t *testing.T) {
+	t.Parallel()
+
+	defer func() {
+		if err, _ := recover().(error); !errors.Is(err, ErrInvalidName) {
+			log.Fatalf("expected a panic with %v, got %v", ErrInvalidName, err)
+		}
+	}()
+	WithSessionAndUserProjection(Dialect{EscapeChar: `"`}, Projection{UserAttributes: []string{"user\x00name"}})
+	log.Fatal("expected WithSessionAndUserProjection to panic")
+}
@@ -0,0 +1,151 @@
+package sqlutil
+
+import (
+	"database/sql/driver"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// ParseTag splits a db tag into the column name and its options, omitempty
+// and readonly. A field without a name isn't a column.
+func ParseTag(tag string) (name string, omitEmpty bool, readOnly bool) {
+	name, options, _ := strings.Cut(tag, ",")
+	for options != "" {
+		var option string
+		option, options, _ = strings.Cut(options, ",")
+		switch option {
+		case "omitempty":
+			omitEmpty = true
+		case "readonly":
+			readOnly = true
+		}
+	}
+	if name == "-" {
+		name = ""
+	}
+	return name, omitEmpty, readOnly
+}
+
+// insertField is a field of a struct written by an insert.
+type insertField struct {
+	// index leads to the field through embedded structs.
+	index     []int
+	name      string
+	omitEmpty bool
+}
+
+// insertFields returns the fields of t written by an insert: those with a
+// db tag that isn't readonly, including those of untagged embedded structs.
+func insertFields(t reflect.Type, index []int) []insertField {
+	var fields []insertField
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		fieldIndex := append(slices.Clip(index), i)
+		tag, tagged := field.Tag.Lookup("db")
+
+		if field.Anonymous && !tagged {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				fields = append(fields, insertFields(embedded, fieldIndex)...)
+			}
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+
+		name, omitEmpty, readOnly := ParseTag(tag)
+		if name == "" || readOnly {
+			continue
+		}
+		fields = append(fields, insertField{
+			index:     fieldIndex,
+			name:      name,
+			omitEmpty: omitEmpty,
+		})
+	}
+	return fields
+}
+
+// insertValue returns the argument for a field. driver.Valuer values are left
+// to the driver; other pointers are dereferenced, nil becoming NULL.
+func insertValue(v reflect.Value) any {
+	if _, ok := v.Interface().(driver.Valuer); ok {
+		return v.Interface()
+	}
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return nil
+		}
+		return v.Elem().Interface()
+	}
+	return v.Interface()
+}
+
+// InsertHelper returns a function listing the columns, the placeholders and
+// the values of an insert of a T. column escapes the column storing the field
+// tagged name. The fields are looked up once, here, rather than on every call.
+//
+// Fields are mapped by their db tag, which can be followed by options:
+// omitempty leaves zero values out so that column defaults apply, and
+// readonly leaves the field out altogether. The fields of untagged embedded
+// structs are included as if they were fields of T; those of a nil embedded
+// pointer are NULL.
+func InsertHelper[T any](
+	placeholder func(index int) string,
+	column func(name string) string,
+) func(values T) ([]string, []string, []any) {
+	columns := insertFields(reflect.TypeOf((*T)(nil)).Elem(), nil)
+
+	fields := make([]string, len(columns))
+	placeholders := make([]string, len(columns))
+	for i, c := range columns {
+		fields[i] = column(c.name)
+		placeholders[i] = placeholder(i)
+	}
+
+	return func(values T) ([]string, []string, []any) {
+		v := reflect.ValueOf(values)
+		args := make([]any, 0, len(columns))
+
+		// kept lists the columns written once one has been left out.
+		var kept []string
+		for i, c := range columns {
+			field, err := v.FieldByIndexErr(c.index)
+			if c.omitEmpty && (err != nil || field.IsZero()) {
+				if kept == nil {
+					kept = append(make([]string, 0, len(columns)), fields[:i]...)
+				}
+				continue
+			}
+			if kept != nil {
+				kept = append(kept, fields[i])
+			}
+			if err != nil {
+				args = append(args, nil)
+			} else {
+				args = append(args, insertValue(field))
+			}
+		}
+
+		// Callers append attributes, so the shared slices are clipped to
+		// make append copy them.
+		if kept == nil {
+			return slices.Clip(fields), slices.Clip(placeholders), args
+		}
+		return kept, slices.Clip(placeholders[:len(kept)]), args
+	}
+}
+
+// SetArgs joins the assignments of an update.
+func SetArgs(fields []string, placeholders []string) string {
+	setArgs := make([]string, len(fields))
+	for i, field := range fields {
+		setArgs[i] = field + " = " + placeholders[i]
+	}
+	return strings.Join(setArgs, ", ")
+}
@@ -0,0 +1,13 @@
+module github.com/seatedro/guam-adapters/memory
+
+go 1.21.0
+
+require (
+	github.com/seatedro/guam v0.0.3
+	github.com/seatedro/guam-adapters/adaptertest v0.0.0
+)
+
+replace (
+	github.com/seatedro/guam => ../../guam
+	github.com/seatedro/guam-adapters/adaptertest => ../adaptertest
+)
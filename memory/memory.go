@@ -0,0 +1,486 @@
+package memory
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/seatedro/guam/auth"
+)
+
+var (
+	ErrDuplicateUserId    = errors.New("memory: duplicate user id")
+	ErrDuplicateSessionId = errors.New("memory: duplicate session id")
+)
+
+// Adapter is the guam adapter returned by MemoryAdapter.
+type Adapter interface {
+	auth.AdapterWithGetter
+
+	// Snapshot returns a copy of everything stored in the adapter.
+	Snapshot() Snapshot
+	// Restore replaces everything stored in the adapter with snapshot.
+	Restore(snapshot Snapshot)
+	// Reset deletes everything stored in the adapter.
+	Reset()
+}
+
+// Snapshot is a copy of the contents of an adapter, taken by Adapter.Snapshot.
+type Snapshot struct {
+	users    map[string]auth.UserSchema
+	sessions map[string]auth.SessionSchema
+	keys     map[string]auth.KeySchema
+}
+
+type memoryAdapterImpl struct {
+	mu       sync.RWMutex
+	users    map[string]auth.UserSchema
+	sessions map[string]auth.SessionSchema
+	keys     map[string]auth.KeySchema
+}
+
+// MemoryAdapter returns an adapter storing users, sessions and keys in memory.
+// It enforces the same constraints as the postgresql adapter: deleting a user
+// cascades to its sessions and keys, and writes referencing a missing user or
+// reusing a key id fail with the same guam errors. Changing the id of a user
+// moves its sessions and keys to the new id.
+func MemoryAdapter() Adapter {
+	m := &memoryAdapterImpl{}
+	m.Reset()
+	return m
+}
+
+func (m *memoryAdapterImpl) Snapshot() Snapshot {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return Snapshot{
+		users:    copyMap(m.users, copyUser),
+		sessions: copyMap(m.sessions, copySession),
+		keys:     copyMap(m.keys, copyKey),
+	}
+}
+
+func (m *memoryAdapterImpl) Restore(snapshot Snapshot) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.users = copyMap(snapshot.users, copyUser)
+	m.sessions = copyMap(snapshot.sessions, copySession)
+	m.keys = copyMap(snapshot.keys, copyKey)
+}
+
+func (m *memoryAdapterImpl) Reset() {
+	m.Restore(Snapshot{})
+}
+
+func copyMap[T any](values map[string]T, copyValue func(T) T) map[string]T {
+	copied := make(map[string]T, len(values))
+	for k, v := range values {
+		copied[k] = copyValue(v)
+	}
+	return copied
+}
+
+func copyAttributes(attributes map[string]any) map[string]any {
+	if attributes == nil {
+		return nil
+	}
+	copied := make(map[string]any, len(attributes))
+	for k, v := range attributes {
+		copied[k] = v
+	}
+	return copied
+}
+
+func copyUser(user auth.UserSchema) auth.UserSchema {
+	user.Attributes = copyAttributes(user.Attributes)
+	return user
+}
+
+func copySession(session auth.SessionSchema) auth.SessionSchema {
+	session.Attributes = copyAttributes(session.Attributes)
+	return session
+}
+
+func copyKey(key auth.KeySchema) auth.KeySchema {
+	if key.HashedPassword != nil {
+		hashedPassword := *key.HashedPassword
+		key.HashedPassword = &hashedPassword
+	}
+	return key
+}
+
+func (m *memoryAdapterImpl) GetUser(
+	userId string,
+) (*auth.UserSchema, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	user, ok := m.users[userId]
+	if !ok {
+		return nil, nil
+	}
+	user = copyUser(user)
+	return &user, nil
+}
+
+func (m *memoryAdapterImpl) SetUser(user auth.UserSchema, key *auth.KeySchema) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.users[user.ID]; ok {
+		return ErrDuplicateUserId
+	}
+	if key != nil {
+		if _, ok := m.users[key.UserID]; !ok && key.UserID != user.ID {
+			return auth.NewGuamError(auth.AUTH_INVALID_USER_ID, "")
+		}
+		if _, ok := m.keys[key.ID]; ok {
+			return auth.NewGuamError(auth.AUTH_DUPLICATE_KEY_ID, "")
+		}
+		m.keys[key.ID] = copyKey(*key)
+	}
+	m.users[user.ID] = copyUser(user)
+	return nil
+}
+
+func (m *memoryAdapterImpl) DeleteUser(userId string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.users, userId)
+	for id, session := range m.sessions {
+		if session.UserID == userId {
+			delete(m.sessions, id)
+		}
+	}
+	for id, key := range m.keys {
+		if key.UserID == userId {
+			delete(m.keys, id)
+		}
+	}
+	return nil
+}
+
+func (m *memoryAdapterImpl) UpdateUser(
+	userId string,
+	partialUser map[string]any,
+) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.users[userId]
+	if !ok {
+		return nil
+	}
+	user = copyUser(user)
+	for key, value := range partialUser {
+		if key == "id" {
+			var err error
+			if user.ID, err = toString(value); err != nil {
+				return err
+			}
+			continue
+		}
+		if user.Attributes == nil {
+			user.Attributes = make(map[string]any)
+		}
+		user.Attributes[key] = value
+	}
+	if user.ID != userId {
+		if _, ok := m.users[user.ID]; ok {
+			return ErrDuplicateUserId
+		}
+		// Move the user's sessions and keys along with it.
+		delete(m.users, userId)
+		for id, session := range m.sessions {
+			if session.UserID == userId {
+				session.UserID = user.ID
+				m.sessions[id] = session
+			}
+		}
+		for id, key := range m.keys {
+			if key.UserID == userId {
+				key.UserID = user.ID
+				m.keys[id] = key
+			}
+		}
+	}
+	m.users[user.ID] = user
+	return nil
+}
+
+func (m *memoryAdapterImpl) GetSession(
+	sessionId string,
+) (*auth.SessionSchema, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	session, ok := m.sessions[sessionId]
+	if !ok {
+		return nil, nil
+	}
+	session = copySession(session)
+	return &session, nil
+}
+
+func (m *memoryAdapterImpl) GetSessionsByUserId(
+	userId string,
+) ([]auth.SessionSchema, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var sessions []auth.SessionSchema
+	for _, session := range m.sessions {
+		if session.UserID == userId {
+			sessions = append(sessions, copySession(session))
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].ID < sessions[j].ID
+	})
+	return sessions, nil
+}
+
+func (m *memoryAdapterImpl) SetSession(
+	session auth.SessionSchema,
+) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.users[session.UserID]; !ok {
+		return auth.NewGuamError(auth.AUTH_INVALID_USER_ID, "")
+	}
+	if _, ok := m.sessions[session.ID]; ok {
+		return ErrDuplicateSessionId
+	}
+	m.sessions[session.ID] = copySession(session)
+	return nil
+}
+
+func (m *memoryAdapterImpl) DeleteSession(
+	sessionId string,
+) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.sessions, sessionId)
+	return nil
+}
+
+func (m *memoryAdapterImpl) DeleteSessionsByUserId(
+	userId string,
+) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for id, session := range m.sessions {
+		if session.UserID == userId {
+			delete(m.sessions, id)
+		}
+	}
+	return nil
+}
+
+func (m *memoryAdapterImpl) UpdateSession(
+	sessionId string,
+	partialSession map[string]any,
+) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	session, ok := m.sessions[sessionId]
+	if !ok {
+		return nil
+	}
+	session = copySession(session)
+	for key, value := range partialSession {
+		var err error
+		switch key {
+		case "id":
+			session.ID, err = toString(value)
+		case "user_id":
+			session.UserID, err = toString(value)
+			if _, ok := m.users[session.UserID]; err == nil && !ok {
+				err = auth.NewGuamError(auth.AUTH_INVALID_USER_ID, "")
+			}
+		case "active_expires":
+			session.ActiveExpires, err = toInt64(value)
+		case "idle_expires":
+			session.IdleExpires, err = toInt64(value)
+		default:
+			if session.Attributes == nil {
+				session.Attributes = make(map[string]any)
+			}
+			session.Attributes[key] = value
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if session.ID != sessionId {
+		if _, ok := m.sessions[session.ID]; ok {
+			return ErrDuplicateSessionId
+		}
+		delete(m.sessions, sessionId)
+	}
+	m.sessions[session.ID] = session
+	return nil
+}
+
+func (m *memoryAdapterImpl) GetKey(keyId string) (*auth.KeySchema, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	key, ok := m.keys[keyId]
+	if !ok {
+		return nil, nil
+	}
+	key = copyKey(key)
+	return &key, nil
+}
+
+func (m *memoryAdapterImpl) GetKeysByUserId(userId string) ([]auth.KeySchema, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var keys []auth.KeySchema
+	for _, key := range m.keys {
+		if key.UserID == userId {
+			keys = append(keys, copyKey(key))
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].ID < keys[j].ID
+	})
+	return keys, nil
+}
+
+func (m *memoryAdapterImpl) SetKey(key auth.KeySchema) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.keys[key.ID]; ok {
+		return auth.NewGuamError(auth.AUTH_DUPLICATE_KEY_ID, "")
+	}
+	if _, ok := m.users[key.UserID]; !ok {
+		return auth.NewGuamError(auth.AUTH_INVALID_USER_ID, "")
+	}
+	m.keys[key.ID] = copyKey(key)
+	return nil
+}
+
+func (m *memoryAdapterImpl) UpdateKey(keyId string, partialKey map[string]any) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key, ok := m.keys[keyId]
+	if !ok {
+		return nil
+	}
+	key = copyKey(key)
+	for k, v := range partialKey {
+		var err error
+		switch k {
+		case "id":
+			key.ID, err = toString(v)
+		case "user_id":
+			key.UserID, err = toString(v)
+			if _, ok := m.users[key.UserID]; err == nil && !ok {
+				err = auth.NewGuamError(auth.AUTH_INVALID_USER_ID, "")
+			}
+		case "hashed_password":
+			key.HashedPassword, err = toStringPointer(v)
+		default:
+			err = fmt.Errorf("memory: key has no column %s", k)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if key.ID != keyId {
+		if _, ok := m.keys[key.ID]; ok {
+			return auth.NewGuamError(auth.AUTH_DUPLICATE_KEY_ID, "")
+		}
+		delete(m.keys, keyId)
+	}
+	m.keys[key.ID] = key
+	return nil
+}
+
+func (m *memoryAdapterImpl) DeleteKey(keyId string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.keys, keyId)
+	return nil
+}
+
+func (m *memoryAdapterImpl) DeleteKeysByUserId(userId string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for id, key := range m.keys {
+		if key.UserID == userId {
+			delete(m.keys, id)
+		}
+	}
+	return nil
+}
+
+func (m *memoryAdapterImpl) GetSessionAndUser(
+	sessionId string,
+) (*auth.SessionSchema, *auth.UserJoinSessionSchema, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	session, ok := m.sessions[sessionId]
+	if !ok {
+		return nil, nil, nil
+	}
+	user, ok := m.users[session.UserID]
+	if !ok {
+		return nil, nil, nil
+	}
+
+	session = copySession(session)
+	joined := auth.UserJoinSessionSchema{UserSchema: copyUser(user), SessionID: session.ID}
+	return &session, &joined, nil
+}
+
+func toString(value any) (string, error) {
+	if s, ok := value.(string); ok {
+		return s, nil
+	}
+	return "", fmt.Errorf("memory: expected a string, got %T", value)
+}
+
+func toStringPointer(value any) (*string, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case *string:
+		if v == nil {
+			return nil, nil
+		}
+		s := *v
+		return &s, nil
+	case string:
+		return &v, nil
+	}
+	return nil, fmt.Errorf("memory: expected a string, got %T", value)
+}
+
+func toInt64(value any) (int64, error) {
+	switch v := value.(type) {
+	case int64:
+		return v, nil
+	case int:
+		return int64(v), nil
+	case int32:
+		return int64(v), nil
+	}
+	return 0, fmt.Errorf("memory: expected an integer, got %T", value)
+}
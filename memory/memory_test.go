@@ -0,0 +1,378 @@
+package memory
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"testing"
+
+	"github.com/seatedro/guam-adapters/adaptertest"
+	"github.com/seatedro/guam/auth"
+	"github.com/seatedro/guam/utils"
+)
+
+func createUser(adapter auth.AdapterWithGetter, withKey bool) (string, string) {
+	var key *auth.KeySchema = nil
+	userId := utils.GenerateRandomString(5, "")
+	user := auth.UserSchema{
+		ID: userId,
+		Attributes: map[string]interface{}{
+			"username": utils.GenerateRandomString(6, ""),
+		},
+	}
+	if withKey {
+		hashedPassword := utils.GenerateScryptHash(utils.GenerateRandomString(6, ""))
+		key = &auth.KeySchema{
+			ID:             utils.GenerateRandomString(5, ""),
+			UserID:         userId,
+			HashedPassword: &hashedPassword,
+		}
+	}
+	err := adapter.SetUser(user, key)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if key == nil {
+		return userId, ""
+	}
+	return userId, key.ID
+}
+
+func createSession(adapter auth.AdapterWithGetter, userId string) string {
+	sessionId := utils.GenerateRandomString(5, "")
+	err := adapter.SetSession(auth.SessionSchema{
+		ID:            sessionId,
+		UserID:        userId,
+		ActiveExpires: 1,
+		IdleExpires:   2,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	return sessionId
+}
+
+func expectGuamError(err error, message auth.ErrorMessage) {
+	var guamErr *auth.GuamError
+	if !errors.As(err, &guamErr) || guamErr.Message != message {
+		log.Fatalf("expected %s, got %v", message, err)
+	}
+}
+
+func TestSetUser(t *testing.T) {
+	adapter := MemoryAdapter()
+
+	userId, keyId := createUser(adapter, true)
+
+	user, err := adapter.GetUser(userId)
+	if err != nil || user == nil || user.Attributes["username"] == nil {
+		log.Fatalf("expected user %s, got %+v: %v", userId, user, err)
+	}
+	key, err := adapter.GetKey(keyId)
+	if err != nil || key == nil || key.UserID != userId {
+		log.Fatalf("expected key %s, got %+v: %v", keyId, key, err)
+	}
+
+	err = adapter.SetUser(auth.UserSchema{ID: userId}, nil)
+	if !errors.Is(err, ErrDuplicateUserId) {
+		log.Fatalf("expected %v, got %v", ErrDuplicateUserId, err)
+	}
+}
+
+func TestSetUserWithDuplicateKeyId(t *testing.T) {
+	adapter := MemoryAdapter()
+
+	_, keyId := createUser(adapter, true)
+
+	userId := utils.GenerateRandomString(5, "")
+	err := adapter.SetUser(auth.UserSchema{ID: userId}, &auth.KeySchema{
+		ID:     keyId,
+		UserID: userId,
+	})
+	expectGuamError(err, auth.AUTH_DUPLICATE_KEY_ID)
+
+	// Nothing is stored when the key can't be.
+	user, err := adapter.GetUser(userId)
+	if err != nil || user != nil {
+		log.Fatal("expected user not to be stored: ", err)
+	}
+}
+
+func TestStoredValuesAreCopied(t *testing.T) {
+	adapter := MemoryAdapter()
+
+	userId, _ := createUser(adapter, false)
+
+	user, _ := adapter.GetUser(userId)
+	user.Attributes["username"] = "changed"
+
+	user, _ = adapter.GetUser(userId)
+	if user.Attributes["username"] == "changed" {
+		log.Fatal("expected stored user not to change")
+	}
+}
+
+func TestDeleteUserCascades(t *testing.T) {
+	adapter := MemoryAdapter()
+
+	userId, keyId := createUser(adapter, true)
+	sessionId := createSession(adapter, userId)
+	otherUserId, otherKeyId := createUser(adapter, true)
+	otherSessionId := createSession(adapter, otherUserId)
+
+	if err := adapter.DeleteUser(userId); err != nil {
+		log.Fatal(err)
+	}
+
+	if session, _ := adapter.GetSession(sessionId); session != nil {
+		log.Fatal("expected session to be deleted")
+	}
+	if key, _ := adapter.GetKey(keyId); key != nil {
+		log.Fatal("expected key to be deleted")
+	}
+	if session, _ := adapter.GetSession(otherSessionId); session == nil {
+		log.Fatal("expected other session to be kept")
+	}
+	if key, _ := adapter.GetKey(otherKeyId); key == nil {
+		log.Fatal("expected other key to be kept")
+	}
+}
+
+func TestUpdateUser(t *testing.T) {
+	adapter := MemoryAdapter()
+
+	userId, _ := createUser(adapter, false)
+
+	err := adapter.UpdateUser(userId, map[string]any{"username": "guam"})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	user, err := adapter.GetUser(userId)
+	if err != nil || user.Attributes["username"] != "guam" {
+		log.Fatalf("expected user to be updated, got %+v: %v", user, err)
+	}
+}
+
+func TestUpdateUserIdMovesSessionsAndKeys(t *testing.T) {
+	adapter := MemoryAdapter()
+
+	userId, keyId := createUser(adapter, true)
+	sessionId := createSession(adapter, userId)
+	otherUserId, _ := createUser(adapter, false)
+
+	if err := adapter.UpdateUser(userId, map[string]any{"id": otherUserId}); !errors.Is(err, ErrDuplicateUserId) {
+		log.Fatalf("expected %v, got %v", ErrDuplicateUserId, err)
+	}
+
+	newUserId := utils.GenerateRandomString(5, "")
+	if err := adapter.UpdateUser(userId, map[string]any{"id": newUserId}); err != nil {
+		log.Fatal(err)
+	}
+	if user, err := adapter.GetUser(userId); err != nil || user != nil {
+		log.Fatalf("expected no user under the old id, got %+v: %v", user, err)
+	}
+	session, user, err := adapter.GetSessionAndUser(sessionId)
+	if err != nil || session == nil || user == nil || user.ID != newUserId {
+		log.Fatalf("expected the session to move to the new id, got %+v %+v: %v", session, user, err)
+	}
+	key, err := adapter.GetKey(keyId)
+	if err != nil || key == nil || key.UserID != newUserId {
+		log.Fatalf("expected the key to move to the new id, got %+v: %v", key, err)
+	}
+}
+
+func TestUpdateSessionDuplicateSessionId(t *testing.T) {
+	adapter := MemoryAdapter()
+
+	userId, _ := createUser(adapter, false)
+	sessionId := createSession(adapter, userId)
+	otherSessionId := createSession(adapter, userId)
+
+	err := adapter.UpdateSession(sessionId, map[string]any{"id": otherSessionId})
+	if !errors.Is(err, ErrDuplicateSessionId) {
+		log.Fatalf("expected %v, got %v", ErrDuplicateSessionId, err)
+	}
+}
+
+func TestSetSessionInvalidUserId(t *testing.T) {
+	adapter := MemoryAdapter()
+
+	err := adapter.SetSession(auth.SessionSchema{
+		ID:     utils.GenerateRandomString(5, ""),
+		UserID: utils.GenerateRandomString(5, ""),
+	})
+	expectGuamError(err, auth.AUTH_INVALID_USER_ID)
+}
+
+func TestSessions(t *testing.T) {
+	adapter := MemoryAdapter()
+
+	userId, _ := createUser(adapter, false)
+	sessionId := createSession(adapter, userId)
+	createSession(adapter, userId)
+
+	sessions, err := adapter.GetSessionsByUserId(userId)
+	if err != nil || len(sessions) != 2 {
+		log.Fatalf("expected 2 sessions, got %+v: %v", sessions, err)
+	}
+
+	err = adapter.UpdateSession(sessionId, map[string]any{
+		"idle_expires": int64(10),
+		"country":      "NZ",
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	session, err := adapter.GetSession(sessionId)
+	if err != nil || session.IdleExpires != 10 || session.Attributes["country"] != "NZ" {
+		log.Fatalf("expected session to be updated, got %+v: %v", session, err)
+	}
+
+	err = adapter.UpdateSession(sessionId, map[string]any{"user_id": "missing"})
+	expectGuamError(err, auth.AUTH_INVALID_USER_ID)
+
+	if err := adapter.DeleteSession(sessionId); err != nil {
+		log.Fatal(err)
+	}
+	if session, _ := adapter.GetSession(sessionId); session != nil {
+		log.Fatal("expected session to be deleted")
+	}
+
+	if err := adapter.DeleteSessionsByUserId(userId); err != nil {
+		log.Fatal(err)
+	}
+	sessions, err = adapter.GetSessionsByUserId(userId)
+	if err != nil || sessions != nil {
+		log.Fatalf("expected no sessions, got %+v: %v", sessions, err)
+	}
+}
+
+func TestKeys(t *testing.T) {
+	adapter := MemoryAdapter()
+
+	userId, keyId := createUser(adapter, true)
+
+	err := adapter.SetKey(auth.KeySchema{ID: keyId, UserID: userId})
+	expectGuamError(err, auth.AUTH_DUPLICATE_KEY_ID)
+
+	err = adapter.SetKey(auth.KeySchema{ID: utils.GenerateRandomString(5, ""), UserID: "missing"})
+	expectGuamError(err, auth.AUTH_INVALID_USER_ID)
+
+	if err := adapter.SetKey(auth.KeySchema{ID: utils.GenerateRandomString(5, ""), UserID: userId}); err != nil {
+		log.Fatal(err)
+	}
+	keys, err := adapter.GetKeysByUserId(userId)
+	if err != nil || len(keys) != 2 {
+		log.Fatalf("expected 2 keys, got %+v: %v", keys, err)
+	}
+
+	hashedPassword := utils.GenerateScryptHash(utils.GenerateRandomString(10, ""))
+	if err := adapter.UpdateKey(keyId, map[string]any{"hashed_password": &hashedPassword}); err != nil {
+		log.Fatal(err)
+	}
+	key, err := adapter.GetKey(keyId)
+	if err != nil || *key.HashedPassword != hashedPassword {
+		log.Fatalf("expected key to be updated, got %+v: %v", key, err)
+	}
+
+	if err := adapter.DeleteKey(keyId); err != nil {
+		log.Fatal(err)
+	}
+	if key, _ := adapter.GetKey(keyId); key != nil {
+		log.Fatal("expected key to be deleted")
+	}
+
+	if err := adapter.DeleteKeysByUserId(userId); err != nil {
+		log.Fatal(err)
+	}
+	keys, err = adapter.GetKeysByUserId(userId)
+	if err != nil || keys != nil {
+		log.Fatalf("expected no keys, got %+v: %v", keys, err)
+	}
+}
+
+func TestGetSessionAndUser(t *testing.T) {
+	adapter := MemoryAdapter()
+
+	userId, _ := createUser(adapter, false)
+	sessionId := createSession(adapter, userId)
+
+	session, user, err := adapter.GetSessionAndUser(sessionId)
+	if err != nil || session.ID != sessionId || user.ID != userId || user.SessionID != sessionId {
+		log.Fatalf("unexpected session and user %+v %+v: %v", session, user, err)
+	}
+
+	session, user, err = adapter.GetSessionAndUser("missing")
+	if err != nil || session != nil || user != nil {
+		log.Fatalf("expected no session and user, got %+v %+v: %v", session, user, err)
+	}
+}
+
+func TestSnapshotRestoreReset(t *testing.T) {
+	adapter := MemoryAdapter()
+
+	userId, _ := createUser(adapter, true)
+	snapshot := adapter.Snapshot()
+
+	otherUserId, _ := createUser(adapter, false)
+	if err := adapter.DeleteUser(userId); err != nil {
+		log.Fatal(err)
+	}
+
+	adapter.Restore(snapshot)
+	if user, _ := adapter.GetUser(userId); user == nil {
+		log.Fatal("expected user to be restored")
+	}
+	if keys, _ := adapter.GetKeysByUserId(userId); len(keys) != 1 {
+		log.Fatal("expected key to be restored")
+	}
+	if user, _ := adapter.GetUser(otherUserId); user != nil {
+		log.Fatal("expected user created after the snapshot to be gone")
+	}
+
+	adapter.Reset()
+	if user, _ := adapter.GetUser(userId); user != nil {
+		log.Fatal("expected adapter to be empty")
+	}
+
+	// The snapshot is unaffected by later writes.
+	adapter.Restore(snapshot)
+	if user, _ := adapter.GetUser(userId); user == nil {
+		log.Fatal("expected snapshot to be reusable")
+	}
+}
+
+func TestConcurrentAccess(t *testing.T) {
+	adapter := MemoryAdapter()
+
+	userId, _ := createUser(adapter, false)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			createSession(adapter, userId)
+		}()
+		go func() {
+			defer wg.Done()
+			if _, err := adapter.GetSessionsByUserId(userId); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	sessions, err := adapter.GetSessionsByUserId(userId)
+	if err != nil || len(sessions) != 50 {
+		log.Fatalf("expected 50 sessions, got %d: %v", len(sessions), err)
+	}
+}
+
+func TestConformance(t *testing.T) {
+	adaptertest.Run(t, func(t *testing.T) auth.AdapterWithGetter {
+		return MemoryAdapter()
+	})
+}
@@ -0,0 +1,52 @@
+package mysql
+
+import (
+	"bytes"
+	"log"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/seatedro/guam-adapters/internal/sqlutil"
+	"github.com/seatedro/guam/utils"
+)
+
+func TestWithSlogLogger(t *testing.T) {
+	ctx, db, _ := setup(t)
+	var buf bytes.Buffer
+	adapter := MySQLAdapter(ctx, db, Tables{
+		User:    "auth_user",
+		Session: "user_session",
+		Key:     "user_key",
+	}, false, WithSlogLogger(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))))
+
+	userId := createUser(adapter, true)
+	keys, err := adapter.GetKeysByUserId(userId)
+	if err != nil || len(keys) != 1 {
+		log.Fatalf("expected one key, got %+v: %v", keys, err)
+	}
+
+	output := buf.String()
+	if !strings.Contains(output, "Keys: ") || !strings.Contains(output, sqlutil.Redacted) {
+		log.Fatalf("expected the keys to be logged, got %s", output)
+	}
+	if strings.Contains(output, *keys[0].HashedPassword) {
+		log.Fatalf("expected the hashed password to be redacted, got %s", output)
+	}
+}
+
+func TestWithNilLogger(t *testing.T) {
+	ctx, db, _ := setup(t)
+	for _, opt := range []Option{WithLogger(nil), WithSlogLogger(nil)} {
+		adapter := MySQLAdapter(ctx, db, Tables{
+			User:    "auth_user",
+			Session: "user_session",
+			Key:     "user_key",
+		}, false, opt)
+
+		// Logging through a nil logger would panic.
+		if _, err := adapter.GetUser(utils.GenerateRandomString(5, "")); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/seatedro/guam-adapters/internal/sqlutil"
+	"github.com/seatedro/guam/auth"
+)
+
+const (
+	badField                  = 1054
+	duplicateEntry            = 1062
+	foreignKeyViolationParent = 1452
+)
+
+type Tables struct {
+	User    string
+	Session string
+	Key     string
+
+	// UserColumns, SessionColumns and KeyColumns rename the columns of the
+	// guam schemas in tables that name them differently, e.g. {"id": "uid"}.
+	UserColumns    Columns
+	SessionColumns Columns
+	KeyColumns     Columns
+}
+
+// Columns maps the columns of a guam schema, named after their db tag, to the
+// columns storing them. Columns missing from the map keep their name.
+type Columns = sqlutil.Columns
+
+// Adapter is the guam adapter returned by MySQLAdapter.
+type Adapter interface {
+	auth.AdapterWithGetter
+
+	// WithContext returns a copy of the adapter whose queries run with ctx
+	// instead of the context passed to MySQLAdapter.
+	WithContext(ctx context.Context) Adapter
+
+	// RefreshColumns forgets the columns read from the database, so that
+	// getters read them again, e.g. after a migration. Columns declared with
+	// WithUserColumns and WithSessionColumns are kept.
+	RefreshColumns()
+}
+
+// Option configures the adapter returned by MySQLAdapter.
+type Option = sqlutil.Option
+
+var driver = sqlutil.Driver{
+	Name:            "mysql",
+	Dialect:         dialect,
+	Constraint:      constraint,
+	UndefinedColumn: undefinedColumn,
+	ConvertValue:    convertValue,
+}
+
+type mysqlAdapterImpl struct {
+	*sqlutil.Adapter
+}
+
+// MySQLAdapter returns an adapter storing users, sessions and keys in db. It
+// works with both MySQL and MariaDB.
+//
+// The table names in tables are part of the program's configuration, so it
+// panics if one can't be escaped; check names coming from elsewhere with
+// ValidateName first.
+func MySQLAdapter(
+	ctx context.Context,
+	db *sql.DB,
+	tables Tables,
+	debugMode bool,
+	opts ...Option,
+) Adapter {
+	return &mysqlAdapterImpl{sqlutil.NewAdapter(ctx, db, driver, sqlutil.Tables(tables), debugMode, opts...)}
+}
+
+func (m *mysqlAdapterImpl) WithContext(ctx context.Context) Adapter {
+	return &mysqlAdapterImpl{m.Adapter.WithContext(ctx)}
+}
+
+// constraint reports the constraint violated by err, if any.
+func constraint(err error) (sqlutil.Constraint, string) {
+	var mysqlErr *mysql.MySQLError
+	if !errors.As(err, &mysqlErr) {
+		return sqlutil.NoConstraint, ""
+	}
+	switch mysqlErr.Number {
+	case duplicateEntry:
+		return sqlutil.UniqueConstraint, mysqlErr.Message
+	case foreignKeyViolationParent:
+		return sqlutil.ForeignKeyConstraint, mysqlErr.Message
+	}
+	return sqlutil.NoConstraint, ""
+}
+
+// undefinedColumn reports whether err is from a query naming an unknown column.
+func undefinedColumn(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == badField
+}
@@ -0,0 +1,374 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"net"
+	"testing"
+
+	sqle "github.com/dolthub/go-mysql-server"
+	"github.com/dolthub/go-mysql-server/memory"
+	"github.com/dolthub/go-mysql-server/server"
+	gmssql "github.com/dolthub/go-mysql-server/sql"
+	vitess "github.com/dolthub/vitess/go/mysql"
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/seatedro/guam-adapters/adaptertest"
+	"github.com/seatedro/guam/auth"
+	"github.com/seatedro/guam/utils"
+)
+
+var schema = []string{
+	`CREATE TABLE auth_user (
+		id VARCHAR(255) PRIMARY KEY,
+		username VARCHAR(255)
+	)`,
+	`CREATE TABLE user_session (
+		id VARCHAR(255) PRIMARY KEY,
+		user_id VARCHAR(255) NOT NULL,
+		active_expires BIGINT UNSIGNED NOT NULL,
+		idle_expires BIGINT UNSIGNED NOT NULL,
+		CONSTRAINT user_session_user_id_fk FOREIGN KEY (user_id) REFERENCES auth_user (id) ON DELETE CASCADE
+	)`,
+	`CREATE TABLE user_key (
+		id VARCHAR(255) PRIMARY KEY,
+		user_id VARCHAR(255) NOT NULL,
+		hashed_password VARCHAR(255),
+		CONSTRAINT user_key_user_id_fk FOREIGN KEY (user_id) REFERENCES auth_user (id) ON DELETE CASCADE
+	)`,
+}
+
+func insert(ctx context.Context, db *sql.DB) (string, string, string) {
+	// Create a new user.
+	userId := utils.GenerateRandomString(5, "")
+	username := utils.GenerateRandomString(6, "")
+	_, err := db.ExecContext(
+		ctx,
+		"INSERT INTO auth_user (id, username) VALUES (?, ?)",
+		userId,
+		username,
+	)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Create a new session.
+	sessionId := utils.GenerateRandomString(5, "")
+	_, err = db.ExecContext(
+		ctx,
+		"INSERT INTO user_session (id, user_id, active_expires, idle_expires) VALUES (?, ?, ?, ?)",
+		sessionId,
+		userId,
+		rand.Int63n(1000000000000),
+		rand.Int63n(1000000000000),
+	)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Create a new key.
+	keyId := utils.GenerateRandomString(5, "")
+	hashedPassword := utils.GenerateScryptHash(utils.GenerateRandomString(6, ""))
+	_, err = db.ExecContext(
+		ctx,
+		"INSERT INTO user_key (id, user_id, hashed_password) VALUES (?, ?, ?)",
+		keyId,
+		userId,
+		hashedPassword,
+	)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return userId, sessionId, keyId
+}
+
+// startServer starts an in-process MySQL-compatible server with an empty
+// guam database and returns its address.
+func startServer(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		log.Fatal(err)
+	}
+	address := listener.Addr().String()
+	listener.Close()
+
+	db := memory.NewDatabase("guam")
+	db.EnablePrimaryKeyIndexes()
+	provider := memory.NewDBProvider(db)
+	engine := sqle.NewDefault(provider)
+
+	s, err := server.NewServer(
+		server.Config{Protocol: "tcp", Address: address},
+		engine,
+		sessionBuilder(provider),
+		nil,
+	)
+	if err != nil {
+		log.Fatal(err)
+	}
+	go s.Start()
+	t.Cleanup(func() { s.Close() })
+
+	return address
+}
+
+// sessionBuilder gives every connection a memory.Session, which keeps the
+// writes of a transaction to itself until it commits, so rollbacks undo them.
+func sessionBuilder(provider *memory.DbProvider) server.SessionBuilder {
+	return func(ctx context.Context, conn *vitess.Conn, addr string) (gmssql.Session, error) {
+		client := gmssql.Client{Capabilities: conn.Capabilities}
+		session := gmssql.NewBaseSessionWithClientServer(addr, client, conn.ConnectionID)
+		return memory.NewSession(session, provider), nil
+	}
+}
+
+// setup connects to a fresh server, so tests don't share state.
+func setup(t *testing.T) (context.Context, *sql.DB, Adapter) {
+	ctx := context.Background()
+	db, err := sql.Open("mysql", fmt.Sprintf("root@tcp(%s)/guam", startServer(t)))
+	if err != nil {
+		log.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	for _, statement := range schema {
+		if _, err := db.ExecContext(ctx, statement); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	adapter := MySQLAdapter(ctx, db, Tables{
+		User:    "auth_user",
+		Session: "user_session",
+		Key:     "user_key",
+	}, false)
+
+	return ctx, db, adapter
+}
+
+func createUser(adapter auth.AdapterWithGetter, withKey bool) string {
+	var key *auth.KeySchema = nil
+	userId := utils.GenerateRandomString(5, "")
+	user := auth.UserSchema{
+		ID: userId,
+		Attributes: map[string]interface{}{
+			"username": utils.GenerateRandomString(6, ""),
+		},
+	}
+	if withKey {
+		hashedPassword := utils.GenerateScryptHash(utils.GenerateRandomString(6, ""))
+		key = &auth.KeySchema{
+			ID:             utils.GenerateRandomString(5, ""),
+			UserID:         userId,
+			HashedPassword: &hashedPassword,
+		}
+	}
+	err := adapter.SetUser(user, key)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return userId
+}
+
+func createSession(adapter auth.AdapterWithGetter) string {
+	userId := createUser(adapter, true)
+
+	sessionId := utils.GenerateRandomString(5, "")
+	session := auth.SessionSchema{
+		ID:            sessionId,
+		UserID:        userId,
+		ActiveExpires: rand.Int63n(1000000000000),
+		IdleExpires:   rand.Int63n(1000000000000),
+	}
+
+	err := adapter.SetSession(session)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return sessionId
+}
+
+func createKey(adapter auth.AdapterWithGetter) string {
+	userId := createUser(adapter, true)
+
+	keyId := utils.GenerateRandomString(5, "")
+	hashedPassword := utils.GenerateScryptHash(utils.GenerateRandomString(6, ""))
+	key := auth.KeySchema{
+		ID:             keyId,
+		UserID:         userId,
+		HashedPassword: &hashedPassword,
+	}
+
+	err := adapter.SetKey(key)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return keyId
+}
+
+func expectGuamError(err error, message auth.ErrorMessage) {
+	var guamErr *auth.GuamError
+	if !errors.As(err, &guamErr) || guamErr.Message != message {
+		log.Fatalf("expected %s, got %v", message, err)
+	}
+}
+
+func TestUpdateUserEmpty(t *testing.T) {
+	_, _, adapter := setup(t)
+
+	userId := createUser(adapter, false)
+
+	if err := adapter.UpdateUser(userId, map[string]interface{}{}); err != nil {
+		log.Fatal(err)
+	}
+
+	user, err := adapter.GetUser(userId)
+	if err != nil || user == nil {
+		log.Fatal("expected user to be kept: ", err)
+	}
+}
+
+func TestUpdateSessionInvalidUserId(t *testing.T) {
+	ctx, db, adapter := setup(t)
+
+	_, sessionId, _ := insert(ctx, db)
+
+	err := adapter.UpdateSession(sessionId, map[string]any{
+		"user_id": utils.GenerateRandomString(5, ""),
+	})
+	expectGuamError(err, auth.AUTH_INVALID_USER_ID)
+}
+
+func TestUpdateKeyInvalidUserId(t *testing.T) {
+	ctx, db, adapter := setup(t)
+
+	_, _, keyId := insert(ctx, db)
+
+	err := adapter.UpdateKey(keyId, map[string]any{
+		"user_id": utils.GenerateRandomString(5, ""),
+	})
+	expectGuamError(err, auth.AUTH_INVALID_USER_ID)
+}
+
+func TestUpdateSessionEmpty(t *testing.T) {
+	_, _, adapter := setup(t)
+
+	sessionId := createSession(adapter)
+
+	if err := adapter.UpdateSession(sessionId, map[string]interface{}{}); err != nil {
+		log.Fatal(err)
+	}
+
+	session, err := adapter.GetSession(sessionId)
+	if err != nil || session == nil {
+		log.Fatal("expected session to be kept: ", err)
+	}
+}
+
+func TestUpdateKeyEmpty(t *testing.T) {
+	_, _, adapter := setup(t)
+
+	keyId := createKey(adapter)
+
+	if err := adapter.UpdateKey(keyId, map[string]interface{}{}); err != nil {
+		log.Fatal(err)
+	}
+
+	key, err := adapter.GetKey(keyId)
+	if err != nil || key == nil {
+		log.Fatal("expected key to be kept: ", err)
+	}
+}
+
+func TestAttributesRoundTrip(t *testing.T) {
+	ctx, db, adapter := setup(t)
+
+	for _, statement := range []string{
+		"ALTER TABLE auth_user ADD COLUMN age INT",
+		"ALTER TABLE user_session ADD COLUMN country VARCHAR(255)",
+	} {
+		if _, err := db.ExecContext(ctx, statement); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	userId := utils.GenerateRandomString(5, "")
+	err := adapter.SetUser(auth.UserSchema{
+		ID:         userId,
+		Attributes: map[string]any{"username": "guam", "age": 30},
+	}, nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	user, err := adapter.GetUser(userId)
+	if err != nil || user == nil {
+		log.Fatal("expected user, got ", err)
+	}
+	if user.Attributes["username"] != "guam" || user.Attributes["age"] != int64(30) {
+		log.Fatalf("unexpected attributes %#v", user.Attributes)
+	}
+
+	sessionId := utils.GenerateRandomString(5, "")
+	err = adapter.SetSession(auth.SessionSchema{
+		ID:            sessionId,
+		UserID:        userId,
+		ActiveExpires: 1,
+		IdleExpires:   2,
+		Attributes:    map[string]any{"country": "NZ"},
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	sessions, err := adapter.GetSessionsByUserId(userId)
+	if err != nil || len(sessions) != 1 || sessions[0].Attributes["country"] != "NZ" {
+		log.Fatalf("unexpected sessions %+v: %v", sessions, err)
+	}
+
+	session, joined, err := adapter.GetSessionAndUser(sessionId)
+	if err != nil || session == nil || joined == nil {
+		log.Fatal("expected session and user, got ", err)
+	}
+	if session.Attributes["country"] != "NZ" || joined.SessionID != sessionId || joined.Attributes["age"] != int64(30) {
+		log.Fatalf("unexpected session %+v and user %+v", session, joined)
+	}
+	if _, ok := joined.Attributes["country"]; ok {
+		log.Fatalf("session attributes leaked into the user: %v", joined.Attributes)
+	}
+}
+
+func TestGettersReturnErrorOnClosedDatabase(t *testing.T) {
+	ctx, db, adapter := setup(t)
+
+	userId, sessionId, keyId := insert(ctx, db)
+	db.Close()
+
+	if _, err := adapter.GetUser(userId); err == nil {
+		log.Fatal("expected GetUser to fail")
+	}
+	if _, err := adapter.GetSession(sessionId); err == nil {
+		log.Fatal("expected GetSession to fail")
+	}
+	if _, err := adapter.GetKey(keyId); err == nil {
+		log.Fatal("expected GetKey to fail")
+	}
+	if _, _, err := adapter.GetSessionAndUser(sessionId); err == nil {
+		log.Fatal("expected GetSessionAndUser to fail")
+	}
+}
+
+func TestConformance(t *testing.T) {
+	adaptertest.Run(t, func(t *testing.T) auth.AdapterWithGetter {
+		ctx, db, adapter := setup(t)
+		if _, err := db.ExecContext(ctx, "ALTER TABLE user_session ADD COLUMN country VARCHAR(255)"); err != nil {
+			log.Fatal(err)
+		}
+		return adapter
+	})
+}
@@ -0,0 +1,25 @@
+package mysql
+
+import "strconv"
+
+// convertValue converts a value read from a column of type databaseType. The
+// driver returns text and, over the text protocol, numbers as []byte, so those
+// are converted according to the column type. Binary columns are left as they
+// are.
+func convertValue(databaseType string, val any) (any, error) {
+	b, ok := val.([]byte)
+	if !ok {
+		return val, nil
+	}
+	switch databaseType {
+	case "TINYINT", "SMALLINT", "MEDIUMINT", "INT", "BIGINT", "YEAR":
+		return strconv.ParseInt(string(b), 10, 64)
+	case "UNSIGNED TINYINT", "UNSIGNED SMALLINT", "UNSIGNED MEDIUMINT", "UNSIGNED INT", "UNSIGNED BIGINT":
+		return strconv.ParseUint(string(b), 10, 64)
+	case "FLOAT", "DOUBLE":
+		return strconv.ParseFloat(string(b), 64)
+	case "BINARY", "VARBINARY", "BLOB", "TINYBLOB", "MEDIUMBLOB", "LONGBLOB", "BIT", "GEOMETRY":
+		return b, nil
+	}
+	return string(b), nil
+}
@@ -0,0 +1,163 @@
+package mysql
+
+import (
+	"fmt"
+	"log"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/seatedro/guam/auth"
+	"github.com/seatedro/guam/utils"
+)
+
+func TestRefreshColumns(t *testing.T) {
+	ctx, db, adapter := setup(t)
+	userId := createUser(adapter, false)
+	if user, err := adapter.GetUser(userId); err != nil || user.Attributes["username"] == nil {
+		log.Fatalf("expected username, got %+v: %v", user, err)
+	}
+
+	if _, err := db.ExecContext(ctx, "ALTER TABLE auth_user ADD COLUMN bio VARCHAR(255) DEFAULT 'bio'"); err != nil {
+		log.Fatal(err)
+	}
+	adapter.RefreshColumns()
+	user, err := adapter.GetUser(userId)
+	if err != nil || user == nil || user.Attributes["bio"] != "bio" {
+		log.Fatalf("expected bio to be read, got %+v: %v", user, err)
+	}
+}
+
+func TestSelectsReadWrittenColumns(t *testing.T) {
+	ctx, db, adapter := setup(t)
+	userId := createUser(adapter, false)
+	if _, err := adapter.GetUser(userId); err != nil {
+		log.Fatal(err)
+	}
+
+	if _, err := db.ExecContext(ctx, "ALTER TABLE auth_user ADD COLUMN bio VARCHAR(255)"); err != nil {
+		log.Fatal(err)
+	}
+	if err := adapter.UpdateUser(userId, map[string]any{"bio": "bio"}); err != nil {
+		log.Fatal(err)
+	}
+	user, err := adapter.GetUser(userId)
+	if err != nil || user == nil || user.Attributes["bio"] != "bio" {
+		log.Fatalf("expected bio to be read, got %+v: %v", user, err)
+	}
+}
+
+// The test server reports unknown columns with a generic error, so only the
+// mapping of MySQL's own error is checked here; sqlite tests the retry.
+func TestUndefinedColumn(t *testing.T) {
+	if !undefinedColumn(fmt.Errorf("select: %w", &mysql.MySQLError{Number: badField})) {
+		log.Fatal("expected an unknown column to be reported")
+	}
+	if undefinedColumn(&mysql.MySQLError{Number: duplicateEntry}) {
+		log.Fatal("expected a duplicate entry not to be reported")
+	}
+}
+
+func TestSessionAndUserProjection(t *testing.T) {
+	ctx, db, _ := setup(t)
+	if _, err := db.ExecContext(ctx, "ALTER TABLE user_session ADD COLUMN country VARCHAR(255)"); err != nil {
+		log.Fatal(err)
+	}
+	adapter := MySQLAdapter(ctx, db, Tables{
+		User:    "auth_user",
+		Session: "user_session",
+		Key:     "user_key",
+	}, false, WithSessionAndUserProjection(Projection{}))
+
+	userId := createUser(adapter, false)
+	sessionId := utils.GenerateRandomString(5, "")
+	err := adapter.SetSession(auth.SessionSchema{
+		ID:            sessionId,
+		UserID:        userId,
+		ActiveExpires: 1,
+		IdleExpires:   2,
+		Attributes:    map[string]any{"country": "NZ"},
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	session, user, err := adapter.GetSessionAndUser(sessionId)
+	if err != nil || session == nil || user == nil {
+		log.Fatal("expected session and user, got ", err)
+	}
+	if session.IdleExpires != 2 || user.ID != userId || len(session.Attributes) != 0 || len(user.Attributes) != 0 {
+		log.Fatalf("expected only the guam columns, got %+v and %+v", session, user)
+	}
+
+	if session, err := adapter.GetSession(sessionId); err != nil || session.Attributes["country"] != "NZ" {
+		log.Fatalf("expected GetSession to read every attribute, got %+v: %v", session, err)
+	}
+}
+
+func TestMappedColumns(t *testing.T) {
+	ctx, db, _ := setup(t)
+	for _, statement := range []string{
+		`CREATE TABLE legacy_user (
+			uid VARCHAR(255) PRIMARY KEY,
+			username VARCHAR(255)
+		)`,
+		`CREATE TABLE legacy_session (
+			id VARCHAR(255) PRIMARY KEY,
+			account_id VARCHAR(255) NOT NULL,
+			active_expires BIGINT UNSIGNED NOT NULL,
+			expires_at_ms BIGINT UNSIGNED NOT NULL,
+			CONSTRAINT legacy_session_account_id_fk FOREIGN KEY (account_id) REFERENCES legacy_user (uid) ON DELETE CASCADE
+		)`,
+		`CREATE TABLE legacy_key (
+			id VARCHAR(255) PRIMARY KEY,
+			account_id VARCHAR(255) NOT NULL,
+			hashed_password VARCHAR(255),
+			CONSTRAINT legacy_key_account_id_fk FOREIGN KEY (account_id) REFERENCES legacy_user (uid) ON DELETE CASCADE
+		)`,
+	} {
+		if _, err := db.ExecContext(ctx, statement); err != nil {
+			log.Fatal(err)
+		}
+	}
+	adapter := MySQLAdapter(ctx, db, Tables{
+		User:           "legacy_user",
+		Session:        "legacy_session",
+		Key:            "legacy_key",
+		UserColumns:    Columns{"id": "uid"},
+		SessionColumns: Columns{"user_id": "account_id", "idle_expires": "expires_at_ms"},
+		KeyColumns:     Columns{"user_id": "account_id"},
+	}, false)
+
+	userId := createUser(adapter, true)
+	sessionId := utils.GenerateRandomString(5, "")
+	err := adapter.SetSession(auth.SessionSchema{ID: sessionId, UserID: userId, ActiveExpires: 1, IdleExpires: 2})
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := adapter.UpdateSession(sessionId, map[string]any{"idle_expires": 3}); err != nil {
+		log.Fatal(err)
+	}
+
+	session, user, err := adapter.GetSessionAndUser(sessionId)
+	if err != nil || session == nil || user == nil {
+		log.Fatal("expected session and user, got ", err)
+	}
+	if session.UserID != userId || session.IdleExpires != 3 || user.ID != userId || user.Attributes["username"] == nil {
+		log.Fatalf("expected the mapped columns to be read, got %+v and %+v", session, user)
+	}
+	if _, ok := session.Attributes["expires_at_ms"]; ok {
+		log.Fatalf("expected the renamed column not to be an attribute, got %v", session.Attributes)
+	}
+
+	keys, err := adapter.GetKeysByUserId(userId)
+	if err != nil || len(keys) != 1 || keys[0].UserID != userId {
+		log.Fatalf("expected the user's key, got %+v: %v", keys, err)
+	}
+
+	if err := adapter.DeleteSessionsByUserId(userId); err != nil {
+		log.Fatal(err)
+	}
+	if sessions, err := adapter.GetSessionsByUserId(userId); err != nil || sessions != nil {
+		log.Fatalf("expected no sessions, got %+v: %v", sessions, err)
+	}
+}
@@ -0,0 +1,70 @@
+package mysql
+
+import "github.com/seatedro/guam-adapters/internal/sqlutil"
+
+const EscapeChar = "`"
+
+// maxIdentifierLength is the longest identifier the database accepts.
+const maxIdentifierLength = 64
+
+var dialect = sqlutil.Dialect{EscapeChar: EscapeChar, MaxIdentifierLength: maxIdentifierLength}
+
+// ErrInvalidName is returned when a table or column name can't be escaped.
+var ErrInvalidName = sqlutil.ErrInvalidName
+
+// EscapeName escapes a table name, which may be schema-qualified. Each part of
+// "schema.table" is quoted separately and embedded quote characters are
+// doubled, so the result always names exactly one table. It panics if the
+// name can't be escaped; check names coming from elsewhere with ValidateName
+// first.
+func EscapeName(val string) string {
+	escaped, err := dialect.EscapeName(val)
+	if err != nil {
+		panic(err)
+	}
+	return escaped
+}
+
+// ValidateName returns an error wrapping ErrInvalidName if EscapeName can't
+// escape val: names with an empty part, a NUL character, invalid UTF-8 or a
+// part longer than the database allows.
+func ValidateName(val string) error {
+	_, err := dialect.EscapeName(val)
+	return err
+}
+
+// EscapeIdentifier escapes a single identifier, such as a column name. Unlike
+// EscapeName, dots are part of the name.
+func EscapeIdentifier(val string) (string, error) {
+	return dialect.EscapeIdentifier(val)
+}
+
+type (
+	PlaceHolderFunc   func(index int) string
+	HelperFunc[T any] func(values T) ([]string, []string, []interface{})
+)
+
+// CreatePreparedStatementHelper returns a function listing the columns, the
+// placeholders and the values of an insert of a T. The fields are looked up
+// once, here, rather than on every call.
+//
+// Fields are mapped by their db tag, which can be followed by options:
+// omitempty leaves zero values out so that column defaults apply, and
+// readonly leaves the field out altogether. The fields of untagged embedded
+// structs are included as if they were fields of T; those of a nil embedded
+// pointer are NULL.
+func CreatePreparedStatementHelper[T any](placeholder PlaceHolderFunc) HelperFunc[T] {
+	return CreateMappedStatementHelper[T](placeholder, nil)
+}
+
+// CreateMappedStatementHelper is CreatePreparedStatementHelper for a table
+// whose columns are renamed by mapping.
+func CreateMappedStatementHelper[T any](placeholder PlaceHolderFunc, mapping Columns) HelperFunc[T] {
+	return sqlutil.InsertHelper[T](placeholder, func(name string) string {
+		return dialect.Column(mapping, name)
+	})
+}
+
+func GetSetArgs(fields []string, placeholders []string) string {
+	return sqlutil.SetArgs(fields, placeholders)
+}
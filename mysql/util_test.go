@@ -0,0 +1,56 @@
+package mysql
+
+import (
+	"database/sql"
+	"log"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/seatedro/guam/auth"
+)
+
+type profile struct {
+	Bio string `db:"bio"`
+}
+
+type extendedUser struct {
+	auth.UserSchema
+	*profile
+	Email     *string        `db:"email"`
+	Nickname  string         `db:"nickname,omitempty"`
+	Country   sql.NullString `db:"country"`
+	CreatedAt time.Time      `db:"created_at,readonly"`
+	internal  string
+}
+
+func TestCreatePreparedStatementHelper(t *testing.T) {
+	helper := CreatePreparedStatementHelper[extendedUser](func(index int) string {
+		return "?"
+	})
+
+	fields, placeholders, args := helper(extendedUser{UserSchema: auth.UserSchema{ID: "user"}})
+	expected := []string{"`id`", "`bio`", "`email`", "`country`"}
+	if !slices.Equal(fields, expected) || len(placeholders) != 4 || len(args) != 4 {
+		log.Fatalf("expected columns %q, got %q %q", expected, fields, placeholders)
+	}
+	if args[0] != "user" || args[1] != nil || args[2] != nil || args[3] != (sql.NullString{}) {
+		log.Fatalf("unexpected args %#v", args)
+	}
+
+	email := "guam@example.com"
+	fields, placeholders, args = helper(extendedUser{
+		UserSchema: auth.UserSchema{ID: "user"},
+		profile:    &profile{Bio: "bio"},
+		Email:      &email,
+		Nickname:   "guam",
+		Country:    sql.NullString{String: "NZ", Valid: true},
+	})
+	expected = []string{"`id`", "`bio`", "`email`", "`nickname`", "`country`"}
+	if !slices.Equal(fields, expected) || len(placeholders) != 5 {
+		log.Fatalf("expected columns %q, got %q %q", expected, fields, placeholders)
+	}
+	if args[1] != "bio" || args[2] != email || args[3] != "guam" || args[4] != (sql.NullString{String: "NZ", Valid: true}) {
+		log.Fatalf("unexpected args %#v", args)
+	}
+}
@@ -0,0 +1,295 @@
+package postgresql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/seatedro/guam-adapters/internal/sqlutil"
+)
+
+// AttributePolicy decides what the adapter does with attribute keys that
+// aren't columns of the user or session table.
+//
+// Keys are checked against the columns declared with WithUserColumns and
+// WithSessionColumns, or else against the columns read from the database.
+// Those are read again before a key is found unknown, so that columns added
+// since are accepted; declare the columns to avoid that query when unknown
+// keys are common.
+type AttributePolicy int
+
+const (
+	// AllowUnknownAttributes turns every attribute key into a column, leaving
+	// it to the database to fail on unknown ones. It is the default.
+	AllowUnknownAttributes AttributePolicy = iota
+	// DropUnknownAttributes silently ignores unknown attribute keys.
+	DropUnknownAttributes
+	// RejectUnknownAttributes fails with an *UnknownAttributeError before
+	// anything is written.
+	RejectUnknownAttributes
+)
+
+// UnknownAttributeError is returned under RejectUnknownAttributes when
+// attributes don't match any column of Table.
+type UnknownAttributeError struct {
+	Table      string
+	Attributes []string
+}
+
+func (e *UnknownAttributeError) Error() string {
+	return fmt.Sprintf(
+		"postgresql: unknown attributes for %s: %s",
+		e.Table,
+		strings.Join(e.Attributes, ", "),
+	)
+}
+
+// Option configures the adapter returned by PostgresAdapter.
+type Option func(p *postgresAdapterImpl)
+
+// WithAttributePolicy sets what happens to attribute keys that aren't columns
+// of the user or session table.
+func WithAttributePolicy(policy AttributePolicy) Option {
+	return func(p *postgresAdapterImpl) {
+		p.attributePolicy = policy
+	}
+}
+
+// WithUserColumns declares the columns of the user table, which getters read
+// and the attribute policy checks against. Without it, they are read from the
+// database the first time they're needed, and again after RefreshColumns.
+func WithUserColumns(columns ...string) Option {
+	return func(p *postgresAdapterImpl) {
+		p.userAttributes.columns = sqlutil.NewColumnSet(columns)
+	}
+}
+
+// WithSessionColumns declares the columns of the session table, which getters
+// read and the attribute policy checks against. Without it, they are read from
+// the database the first time they're needed, and again after RefreshColumns.
+func WithSessionColumns(columns ...string) Option {
+	return func(p *postgresAdapterImpl) {
+		p.sessionAttributes.columns = sqlutil.NewColumnSet(columns)
+	}
+}
+
+// WithJSONAttributes stores the attributes of users and sessions in column, a
+// jsonb column of both tables, instead of one column per attribute. Updates
+// merge the given keys into the stored object. The attribute policy doesn't
+// apply, since any key can be stored.
+//
+// The column can be added by Migrate:
+//
+//	UserAttributes: []Column{{Name: "attributes", Type: "JSONB NOT NULL DEFAULT '{}'"}}
+//
+// Like table names, column is part of the program's configuration, so
+// PostgresAdapter panics if it can't be escaped.
+func WithJSONAttributes(column string) Option {
+	return func(p *postgresAdapterImpl) {
+		p.jsonAttributes = column
+		p.escapedJSONAttributes = mustEscapeIdentifier(column)
+	}
+}
+
+// attributeTable describes where the attributes of users or sessions go.
+type attributeTable struct {
+	name    string
+	escaped string
+	// columns are the columns of the table, checked by the attribute policy.
+	columns *sqlutil.ColumnSet
+	// core are the fields of the guam schema, which are never attributes,
+	// and mapping renames their columns.
+	core        map[string]bool
+	coreColumns []string
+	mapping     Columns
+}
+
+func newAttributeTable[T any](name string, escaped string, mapping Columns) *attributeTable {
+	table := &attributeTable{
+		name:    name,
+		escaped: escaped,
+		columns: &sqlutil.ColumnSet{},
+		core:    make(map[string]bool),
+		mapping: mapping,
+	}
+	for _, column := range expectedColumns[T]() {
+		table.core[column.name] = true
+		table.coreColumns = append(table.coreColumns, column.name)
+	}
+	return table
+}
+
+// escapedCoreColumns returns the columns of the guam schema as selected,
+// aliased to their field names if they're renamed.
+func (t *attributeTable) escapedCoreColumns() []string {
+	columns := make([]string, len(t.coreColumns))
+	for i, column := range t.coreColumns {
+		columns[i] = dialect.SelectColumn(t.mapping, column)
+	}
+	return columns
+}
+
+// isCoreColumn reports whether column of the table stores a field of the
+// guam schema, or is named after one, so it can't be read as an attribute.
+func (t *attributeTable) isCoreColumn(column string) bool {
+	if t.core[column] {
+		return true
+	}
+	for _, core := range t.coreColumns {
+		if t.mapping.Column(core) == column {
+			return true
+		}
+	}
+	return false
+}
+
+// columnsOf returns the columns storing keys.
+func (t *attributeTable) columnsOf(keys []string) []string {
+	columns := make([]string, len(keys))
+	for i, key := range keys {
+		columns[i] = t.mapping.Column(key)
+	}
+	return columns
+}
+
+// knownColumns returns the columns of table, loading them on first use.
+func (p *postgresAdapterImpl) knownColumns(ctx context.Context, table *attributeTable) (map[string]bool, error) {
+	return table.columns.Get(func() ([]string, error) {
+		types, err := p.columnTypes(ctx, table.escaped)
+		if err != nil {
+			return nil, err
+		}
+		columns := make([]string, 0, len(types))
+		for column := range types {
+			columns = append(columns, column)
+		}
+		return columns, nil
+	})
+}
+
+// filterAttributes applies the attribute policy to attributes bound for table.
+func (p *postgresAdapterImpl) filterAttributes(
+	table *attributeTable,
+	attributes map[string]any,
+) (map[string]any, error) {
+	if p.attributePolicy == AllowUnknownAttributes || len(attributes) == 0 {
+		return attributes, nil
+	}
+
+	known, err := p.knownColumns(p.ctx, table)
+	if err != nil {
+		return nil, err
+	}
+	unknown := unknownAttributes(table, known, attributes)
+	if unknown != nil && table.columns.Missing(table.columnsOf(unknown)) {
+		// The columns may have been added since they were read.
+		p.logger.Debugln("Reading columns again for: ", unknown)
+		p.refreshColumns()
+		if known, err = p.knownColumns(p.ctx, table); err != nil {
+			return nil, err
+		}
+		unknown = unknownAttributes(table, known, attributes)
+	}
+	if unknown == nil {
+		return attributes, nil
+	}
+
+	if p.attributePolicy == RejectUnknownAttributes {
+		sort.Strings(unknown)
+		return nil, &UnknownAttributeError{Table: table.name, Attributes: unknown}
+	}
+	p.logger.Debugln("Dropping unknown attributes: ", unknown)
+	filtered := make(map[string]any, len(attributes)-len(unknown))
+	for key, val := range attributes {
+		if known[table.mapping.Column(key)] {
+			filtered[key] = val
+		}
+	}
+	return filtered, nil
+}
+
+// unknownAttributes returns the keys of attributes without a column in known.
+func unknownAttributes(table *attributeTable, known map[string]bool, attributes map[string]any) []string {
+	var unknown []string
+	for key := range attributes {
+		if !known[table.mapping.Column(key)] {
+			unknown = append(unknown, key)
+		}
+	}
+	return unknown
+}
+
+// insertAttributes appends the columns storing attributes to an insert into
+// table: one column per attribute, or the JSON attributes column.
+func (p *postgresAdapterImpl) insertAttributes(
+	table *attributeTable,
+	fields []string,
+	placeholders []string,
+	args []any,
+	attributes map[string]any,
+) ([]string, []string, []any, error) {
+	if p.escapedJSONAttributes == "" {
+		attributes, err := p.filterAttributes(table, attributes)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		return appendAttributes(fields, placeholders, args, attributes)
+	}
+
+	if attributes == nil {
+		attributes = map[string]any{}
+	}
+	data, err := json.Marshal(attributes)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	fields = append(fields, p.escapedJSONAttributes)
+	args = append(args, data)
+	placeholders = append(placeholders, fmt.Sprintf("$%d", len(args)))
+	return fields, placeholders, args, nil
+}
+
+// updateAttributes returns the assignments of an update of table setting the
+// values of partial. In JSON mode, keys that aren't columns of the guam schema
+// are merged into the JSON attributes column.
+func (p *postgresAdapterImpl) updateAttributes(
+	table *attributeTable,
+	partial map[string]any,
+) ([]string, []string, []any, error) {
+	if p.escapedJSONAttributes == "" {
+		partial, err := p.filterAttributes(table, partial)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		return appendAttributes(nil, nil, nil, table.mapping.Rename(partial))
+	}
+
+	core := make(map[string]any)
+	attributes := make(map[string]any)
+	for key, val := range partial {
+		if table.core[key] {
+			core[key] = val
+		} else {
+			attributes[key] = val
+		}
+	}
+
+	fields, placeholders, args, err := appendAttributes(nil, nil, nil, table.mapping.Rename(core))
+	if err != nil || len(attributes) == 0 {
+		return fields, placeholders, args, err
+	}
+	data, err := json.Marshal(attributes)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	fields = append(fields, p.escapedJSONAttributes)
+	args = append(args, data)
+	placeholders = append(placeholders, fmt.Sprintf(
+		"COALESCE(%s, '{}'::jsonb) || $%d",
+		p.escapedJSONAttributes,
+		len(args),
+	))
+	return fields, placeholders, args, nil
+}
@@ -0,0 +1,269 @@
+package postgresql
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/seatedro/guam/auth"
+	"github.com/seatedro/guam/utils"
+)
+
+func TestFilterAttributesWithConfiguredColumns(t *testing.T) {
+	t.Parallel()
+
+	tables := Tables{User: "auth_user", Session: "user_session", Key: "user_key"}
+	attributes := map[string]any{"username": "guam", "usernme": "guam"}
+
+	// A nil DB proves the configured columns are used instead of the schema.
+	reject := PostgresAdapter(context.Background(), nil, tables, false,
+		WithAttributePolicy(RejectUnknownAttributes),
+		WithUserColumns("id", "username"),
+	).(*postgresAdapterImpl)
+	_, err := reject.filterAttributes(reject.userAttributes, attributes)
+	var unknownErr *UnknownAttributeError
+	if !errors.As(err, &unknownErr) ||
+		unknownErr.Table != "auth_user" ||
+		len(unknownErr.Attributes) != 1 ||
+		unknownErr.Attributes[0] != "usernme" {
+		t.Fatal("expected an UnknownAttributeError for usernme, got ", err)
+	}
+
+	drop := PostgresAdapter(context.Background(), nil, tables, false,
+		WithAttributePolicy(DropUnknownAttributes),
+		WithUserColumns("id", "username"),
+	).(*postgresAdapterImpl)
+	filtered, err := drop.filterAttributes(drop.userAttributes, attributes)
+	if err != nil || len(filtered) != 1 || filtered["username"] != "guam" {
+		t.Fatalf("expected only username to be kept, got %v: %v", filtered, err)
+	}
+
+	allow := PostgresAdapter(context.Background(), nil, tables, false).(*postgresAdapterImpl)
+	filtered, err = allow.filterAttributes(allow.userAttributes, attributes)
+	if err != nil || len(filtered) != 2 {
+		t.Fatalf("expected every attribute to be kept, got %v: %v", filtered, err)
+	}
+}
+
+func TestRejectUnknownAttributes(t *testing.T) {
+	t.Parallel()
+
+	ctx, conn, _ := setup(t)
+	adapter := PostgresAdapter(ctx, conn, Tables{
+		User:    "auth_user",
+		Session: "user_session",
+		Key:     "user_key",
+	}, false, WithAttributePolicy(RejectUnknownAttributes))
+
+	userId := utils.GenerateRandomString(5, "")
+	err := adapter.SetUser(auth.UserSchema{
+		ID:         userId,
+		Attributes: map[string]any{"usernme": "guam"},
+	}, nil)
+	var unknownErr *UnknownAttributeError
+	if !errors.As(err, &unknownErr) {
+		t.Fatal("expected an UnknownAttributeError, got ", err)
+	}
+	if user, err := adapter.GetUser(userId); err != nil || user != nil {
+		t.Fatal("expected user not to be stored: ", err)
+	}
+
+	userId = createUser(t, adapter, false)
+	err = adapter.UpdateUser(userId, map[string]any{"usernme": "guam"})
+	if !errors.As(err, &unknownErr) {
+		t.Fatal("expected an UnknownAttributeError, got ", err)
+	}
+
+	err = adapter.SetSession(auth.SessionSchema{
+		ID:         utils.GenerateRandomString(5, ""),
+		UserID:     userId,
+		Attributes: map[string]any{"country": "NZ"},
+	})
+	if !errors.As(err, &unknownErr) || unknownErr.Table != "user_session" {
+		t.Fatal("expected an UnknownAttributeError for the session table, got ", err)
+	}
+}
+
+func TestRejectUnknownAttributesAcceptsNewColumns(t *testing.T) {
+	t.Parallel()
+
+	ctx, conn, _ := setup(t)
+	adapter := PostgresAdapter(ctx, conn, Tables{
+		User:    "auth_user",
+		Session: "user_session",
+		Key:     "user_key",
+	}, false, WithAttributePolicy(RejectUnknownAttributes))
+
+	userId := createUser(t, adapter, false)
+	var unknownErr *UnknownAttributeError
+	if err := adapter.UpdateUser(userId, map[string]any{"bio": "bio"}); !errors.As(err, &unknownErr) {
+		t.Fatal("expected an UnknownAttributeError, got ", err)
+	}
+
+	// The columns read by the rejected update don't have bio, but they are
+	// read again before it is rejected.
+	if _, err := conn.Exec(ctx, "ALTER TABLE auth_user ADD COLUMN bio TEXT"); err != nil {
+		t.Fatal(err)
+	}
+	if err := adapter.UpdateUser(userId, map[string]any{"bio": "bio"}); err != nil {
+		t.Fatal(err)
+	}
+	if user, err := adapter.GetUser(userId); err != nil || user.Attributes["bio"] != "bio" {
+		t.Fatalf("expected bio to be read, got %+v: %v", user, err)
+	}
+}
+
+func TestDropUnknownAttributes(t *testing.T) {
+	t.Parallel()
+
+	ctx, conn, _ := setup(t)
+	adapter := PostgresAdapter(ctx, conn, Tables{
+		User:    "auth_user",
+		Session: "user_session",
+		Key:     "user_key",
+	}, false, WithAttributePolicy(DropUnknownAttributes))
+
+	userId := utils.GenerateRandomString(5, "")
+	err := adapter.SetUser(auth.UserSchema{
+		ID:         userId,
+		Attributes: map[string]any{"username": "guam", "usernme": "guam"},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// An update left without columns is a no-op.
+	if err := adapter.UpdateUser(userId, map[string]any{"usernme": "guam"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var username string
+	err = conn.QueryRow(ctx, "SELECT username FROM auth_user WHERE id = $1", userId).Scan(&username)
+	if err != nil || username != "guam" {
+		t.Fatalf("expected username guam, got %q: %v", username, err)
+	}
+}
+
+func TestUpdateJSONAttributes(t *testing.T) {
+	t.Parallel()
+
+	adapter := PostgresAdapter(context.Background(), nil, Tables{User: "auth_user"}, false,
+		WithJSONAttributes("attributes"),
+	).(*postgresAdapterImpl)
+
+	fields, placeholders, args, err := adapter.updateAttributes(adapter.userAttributes, map[string]any{
+		"id":       "user",
+		"username": "guam",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	set := GetSetArgs(fields, placeholders)
+	expected := `"id" = $1, "attributes" = COALESCE("attributes", '{}'::jsonb) || $2`
+	if set != expected {
+		t.Fatalf("expected %s, got %s", expected, set)
+	}
+	if len(args) != 2 || args[0] != "user" || string(args[1].([]byte)) != `{"username":"guam"}` {
+		t.Fatalf("unexpected args %v", args)
+	}
+}
+
+func TestJSONAttributesColumnName(t *testing.T) {
+	t.Parallel()
+
+	tables := Tables{User: "auth_user", Session: "user_session", Key: "user_key"}
+
+	// Dots are part of a column name, not a schema separator.
+	adapter := PostgresAdapter(context.Background(), nil, tables, false,
+		WithJSONAttributes("profile.attributes"),
+	).(*postgresAdapterImpl)
+	if adapter.escapedJSONAttributes != `"profile.attributes"` {
+		t.Fatalf("expected a single identifier, got %s", adapter.escapedJSONAttributes)
+	}
+
+	// An invalid name fails the constructor, like an invalid table name.
+	defer func() {
+		if err, _ := recover().(error); !errors.Is(err, ErrInvalidName) {
+			t.Fatalf("expected a panic with %v, got %v", ErrInvalidName, err)
+		}
+	}()
+	PostgresAdapter(context.Background(), nil, tables, false, WithJSONAttributes(""))
+	t.Fatal("expected PostgresAdapter to panic")
+}
+
+func TestJSONAttributes(t *testing.T) {
+	t.Parallel()
+
+	ctx, conn, _ := connect(t)
+	tables := Tables{
+		User:    "auth_user",
+		Session: "user_session",
+		Key:     "user_key",
+	}
+	attributes := []Column{{Name: "attributes", Type: "JSONB NOT NULL DEFAULT '{}'"}}
+	err := Migrate(ctx, conn, tables, MigrateOptions{
+		UserAttributes:    attributes,
+		SessionAttributes: attributes,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	adapter := PostgresAdapter(ctx, conn, tables, false, WithJSONAttributes("attributes"))
+
+	userId := utils.GenerateRandomString(5, "")
+	err = adapter.SetUser(auth.UserSchema{
+		ID:         userId,
+		Attributes: map[string]any{"username": "guam", "admin": false},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := adapter.UpdateUser(userId, map[string]any{"admin": true, "email": "guam@example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	user, err := adapter.GetUser(userId)
+	if err != nil || user == nil {
+		t.Fatal("expected user, got ", err)
+	}
+	if user.Attributes["username"] != "guam" ||
+		user.Attributes["admin"] != true ||
+		user.Attributes["email"] != "guam@example.com" {
+		t.Fatalf("expected merged attributes, got %v", user.Attributes)
+	}
+
+	sessionId := utils.GenerateRandomString(5, "")
+	err = adapter.SetSession(auth.SessionSchema{
+		ID:            sessionId,
+		UserID:        userId,
+		ActiveExpires: 1,
+		IdleExpires:   2,
+		Attributes:    map[string]any{"country": "NZ"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := adapter.UpdateSession(sessionId, map[string]any{"idle_expires": 3, "ip": "::1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	session, err := adapter.GetSession(sessionId)
+	if err != nil || session == nil {
+		t.Fatal("expected session, got ", err)
+	}
+	if session.IdleExpires != 3 || session.Attributes["country"] != "NZ" || session.Attributes["ip"] != "::1" {
+		t.Fatalf("unexpected session %+v", session)
+	}
+
+	sessions, err := adapter.GetSessionsByUserId(userId)
+	if err != nil || len(sessions) != 1 || sessions[0].Attributes["ip"] != "::1" {
+		t.Fatalf("unexpected sessions %+v: %v", sessions, err)
+	}
+
+	session, joined, err := adapter.GetSessionAndUser(sessionId)
+	if err != nil || session == nil || joined == nil {
+		t.Fatal("expected session and user, got ", err)
+	}
+	if session.Attributes["country"] != "NZ" || joined.Attributes["username"] != "guam" {
+		t.Fatalf("unexpected session %+v and user %+v", session, joined)
+	}
+}
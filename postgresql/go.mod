@@ -3,10 +3,13 @@ module github.com/seatedro/guam-adapters/postgresql
 go 1.21.0
 
 require (
+	github.com/fergusstrange/embedded-postgres v1.25.0
 	github.com/georgysavva/scany/v2 v2.0.0
 	github.com/jackc/pgx/v5 v5.5.1
 	github.com/joho/godotenv v1.5.1
 	github.com/seatedro/guam v0.0.3
+	github.com/seatedro/guam-adapters/adaptertest v0.0.0
+	github.com/seatedro/guam-adapters/internal v0.0.0
 	go.uber.org/zap v1.26.0
 )
 
@@ -14,10 +17,16 @@ require (
 	github.com/jackc/pgpassfile v1.0.0 // indirect
 	github.com/jackc/pgservicefile v0.0.0-20221227161230-091c0ba34f0a // indirect
 	github.com/jackc/puddle/v2 v2.2.1 // indirect
+	github.com/lib/pq v1.10.4 // indirect
+	github.com/xi2/xz v0.0.0-20171230120015-48954b6210f8 // indirect
 	go.uber.org/multierr v1.11.0 // indirect
 	golang.org/x/crypto v0.17.0 // indirect
 	golang.org/x/sync v0.1.0 // indirect
 	golang.org/x/text v0.14.0 // indirect
 )
 
-replace github.com/seatedro/guam => ../../guam
+replace (
+	github.com/seatedro/guam => ../../guam
+	github.com/seatedro/guam-adapters/adaptertest => ../adaptertest
+	github.com/seatedro/guam-adapters/internal => ../internal
+)
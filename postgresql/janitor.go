@@ -0,0 +1,66 @@
+package postgresql
+
+import (
+	"context"
+	"time"
+)
+
+// DefaultJanitorInterval is the time between two sweeps of a janitor started
+// without an Interval.
+const DefaultJanitorInterval = time.Hour
+
+// JanitorOptions configures StartJanitor.
+type JanitorOptions struct {
+	// Interval between two sweeps. Defaults to DefaultJanitorInterval.
+	Interval time.Duration
+	// OnError is called when a sweep fails. The next sweep still runs at the
+	// following interval.
+	OnError func(err error)
+}
+
+// Janitor periodically deletes expired sessions in the background.
+type Janitor struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// StartJanitor starts a goroutine calling adapter.DeleteExpiredSessions every
+// opts.Interval until ctx is done or Stop is called.
+func StartJanitor(ctx context.Context, adapter Adapter, opts JanitorOptions) *Janitor {
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultJanitorInterval
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	j := &Janitor{
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+
+	go func() {
+		defer close(j.done)
+
+		ticker := time.NewTicker(opts.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				_, err := adapter.DeleteExpiredSessions(ctx, now)
+				if err != nil && ctx.Err() == nil && opts.OnError != nil {
+					opts.OnError(err)
+				}
+			}
+		}
+	}()
+
+	return j
+}
+
+// Stop stops the janitor, aborting any sweep in progress, and waits for its
+// goroutine to exit. Batches already deleted stay deleted.
+func (j *Janitor) Stop() {
+	j.cancel()
+	<-j.done
+}
@@ -0,0 +1,89 @@
+package postgresql
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/seatedro/guam/auth"
+	"github.com/seatedro/guam/utils"
+)
+
+// createSessions creates a user with expired sessions, which idled out an hour
+// ago, and live sessions, which idle out in an hour.
+func createSessions(t testing.TB, adapter Adapter, expired int, live int) string {
+	userId := createUser(t, adapter, false)
+	now := time.Now()
+	for i := 0; i < expired+live; i++ {
+		idleExpires := now.Add(time.Hour)
+		if i < expired {
+			idleExpires = now.Add(-time.Hour)
+		}
+		err := adapter.SetSession(auth.SessionSchema{
+			ID:            utils.GenerateRandomString(10, ""),
+			UserID:        userId,
+			ActiveExpires: idleExpires.Add(-time.Minute).UnixMilli(),
+			IdleExpires:   idleExpires.UnixMilli(),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return userId
+}
+
+func TestDeleteExpiredSessions(t *testing.T) {
+	t.Parallel()
+
+	ctx, _, adapter := setup(t)
+	userId := createSessions(t, adapter, expiredSessionsBatchSize+5, 3)
+
+	deleted, err := adapter.DeleteExpiredSessions(ctx, time.Now())
+	if err != nil || deleted != expiredSessionsBatchSize+5 {
+		t.Fatalf("expected %d deleted sessions, got %d: %v", expiredSessionsBatchSize+5, deleted, err)
+	}
+
+	sessions, err := adapter.GetSessionsByUserId(userId)
+	if err != nil || len(sessions) != 3 {
+		t.Fatalf("expected 3 live sessions, got %d: %v", len(sessions), err)
+	}
+}
+
+func TestJanitor(t *testing.T) {
+	t.Parallel()
+
+	ctx, conn, _ := setup(t)
+
+	// The janitor queries concurrently with the test, so it needs a pool.
+	adapter := PostgresAdapter(ctx, newPool(t, ctx, conn), Tables{
+		User:    "auth_user",
+		Session: "user_session",
+		Key:     "user_key",
+	}, false)
+	userId := createSessions(t, adapter, 2, 1)
+
+	janitor := StartJanitor(context.Background(), adapter, JanitorOptions{
+		Interval: 10 * time.Millisecond,
+		OnError: func(err error) {
+			t.Error(err)
+		},
+	})
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		sessions, err := adapter.GetSessionsByUserId(userId)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(sessions) == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected expired sessions to be deleted, got %+v", sessions)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	janitor.Stop()
+	janitor.Stop()
+}
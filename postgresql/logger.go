@@ -0,0 +1,32 @@
+package postgresql
+
+import (
+	"log/slog"
+
+	"github.com/seatedro/guam-adapters/internal/sqlutil"
+	"go.uber.org/zap"
+)
+
+// logger is the subset of *zap.SugaredLogger used by the adapter.
+type logger = sqlutil.Logger
+
+// WithLogger logs through l instead of a logger built from debugMode. A nil l
+// keeps the default logger.
+func WithLogger(l *zap.Logger) Option {
+	return func(p *postgresAdapterImpl) {
+		if l != nil {
+			p.logger = l.Sugar()
+		}
+	}
+}
+
+// WithSlogLogger logs through l instead of a logger built from debugMode.
+// Queries and results are logged at debug level, failures at error level. A
+// nil l keeps the default logger.
+func WithSlogLogger(l *slog.Logger) Option {
+	return func(p *postgresAdapterImpl) {
+		if l != nil {
+			p.logger = sqlutil.SlogLogger{L: l}
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package postgresql
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/seatedro/guam-adapters/internal/sqlutil"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestWithLogger(t *testing.T) {
+	t.Parallel()
+
+	core, logs := observer.New(zap.DebugLevel)
+	adapter := PostgresAdapter(context.Background(), nil, Tables{User: "auth_user", Key: "user_key"}, false,
+		WithLogger(zap.New(core)),
+		WithAttributePolicy(RejectUnknownAttributes),
+		WithUserColumns("id"),
+	)
+
+	if err := adapter.UpdateUser("user", map[string]any{"nickname": "guam"}); err == nil {
+		t.Fatal("expected an unknown attribute error")
+	}
+	if logs.FilterMessageSnippet("unknown attributes").Len() != 1 {
+		t.Fatalf("expected the error to be logged, got %v", logs.All())
+	}
+}
+
+func TestWithNilLogger(t *testing.T) {
+	t.Parallel()
+
+	for _, opt := range []Option{WithLogger(nil), WithSlogLogger(nil)} {
+		adapter := PostgresAdapter(context.Background(), nil, Tables{User: "auth_user", Key: "user_key"}, false,
+			opt,
+			WithAttributePolicy(RejectUnknownAttributes),
+			WithUserColumns("id"),
+		)
+		if err := adapter.UpdateUser("user", map[string]any{"nickname": "guam"}); err == nil {
+			t.Fatal("expected an unknown attribute error")
+		}
+	}
+}
+
+func TestWithSlogLogger(t *testing.T) {
+	t.Parallel()
+
+	ctx, conn, _ := setup(t)
+	var buf bytes.Buffer
+	adapter := PostgresAdapter(ctx, conn, Tables{
+		User:    "auth_user",
+		Session: "user_session",
+		Key:     "user_key",
+	}, false, WithSlogLogger(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))))
+
+	userId := createUser(t, adapter, true)
+	keys, err := adapter.GetKeysByUserId(userId)
+	if err != nil || len(keys) != 1 {
+		t.Fatalf("expected one key, got %+v: %v", keys, err)
+	}
+
+	output := buf.String()
+	if !strings.Contains(output, "Keys: ") || !strings.Contains(output, sqlutil.Redacted) {
+		t.Fatalf("expected the keys to be logged, got %s", output)
+	}
+	if strings.Contains(output, *keys[0].HashedPassword) {
+		t.Fatalf("expected the hashed password to be redacted, got %s", output)
+	}
+}
+
+func TestDefaultLoggerIsNotGlobal(t *testing.T) {
+	t.Parallel()
+
+	before := zap.L()
+	PostgresAdapter(context.Background(), nil, Tables{User: "auth_user", Key: "user_key"}, true)
+	if zap.L() != before {
+		t.Fatal("expected the global logger to be left alone")
+	}
+}
@@ -0,0 +1,204 @@
+package postgresql
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	embeddedpostgres "github.com/fergusstrange/embedded-postgres"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/joho/godotenv"
+	"github.com/seatedro/guam/utils"
+)
+
+// databaseURL points at the server every test connects to. Each test creates
+// its own schema in it, so tests can run in parallel.
+var databaseURL string
+
+var (
+	startOnce  sync.Once
+	startErr   error
+	server     *embeddedpostgres.EmbeddedPostgres
+	runtimeDir string
+)
+
+// TestMain uses the server in DATABASE_URL (or .env) if there is one. Otherwise
+// startServer starts a throwaway embedded Postgres, which is stopped here once
+// every test has run.
+//
+// The embedded Postgres runs the binaries in EMBEDDED_POSTGRES_BINARIES if it
+// is set, else those of a Postgres installed on this machine, found through
+// pg_ctl on the PATH or under /usr/lib/postgresql. Only without either are
+// they downloaded from Maven Central, into EMBEDDED_POSTGRES_CACHE if it is
+// set, so offline runs need one of the two.
+//
+// Tests needing a database fail if there's no server to connect to, so that
+// they can't be skipped unnoticed. Set SKIP_POSTGRES_TESTS to skip them
+// instead.
+func TestMain(m *testing.M) {
+	_ = godotenv.Load()
+	databaseURL = os.Getenv("DATABASE_URL")
+
+	code := m.Run()
+
+	if server != nil {
+		if err := server.Stop(); err != nil {
+			log.Println("Error stopping embedded postgres: ", err)
+		}
+		os.RemoveAll(runtimeDir)
+	}
+	os.Exit(code)
+}
+
+// startServer starts the embedded Postgres the first time a test needs a
+// database, so tests that don't, like the fuzz tests, run without one. If it
+// can't be started, the error is returned to every caller.
+func startServer() error {
+	startOnce.Do(func() {
+		if databaseURL != "" {
+			return
+		}
+
+		dir, err := os.MkdirTemp("", "guam-postgres-")
+		if err != nil {
+			startErr = err
+			return
+		}
+		port, err := freePort()
+		if err != nil {
+			os.RemoveAll(dir)
+			startErr = err
+			return
+		}
+
+		config := embeddedpostgres.DefaultConfig().
+			Port(port).
+			RuntimePath(dir).
+			Logger(nil)
+		if path := os.Getenv("EMBEDDED_POSTGRES_BINARIES"); path != "" {
+			config = config.BinariesPath(path)
+		} else if path := localBinaries(); path != "" {
+			config = config.BinariesPath(path)
+		}
+		if path := os.Getenv("EMBEDDED_POSTGRES_CACHE"); path != "" {
+			config = config.CachePath(path)
+		}
+		db := embeddedpostgres.NewDatabase(config)
+		if err := db.Start(); err != nil {
+			os.RemoveAll(dir)
+			startErr = fmt.Errorf("starting embedded postgres: %w", err)
+			return
+		}
+		server, runtimeDir = db, dir
+		databaseURL = config.GetConnectionURL() + "?sslmode=disable"
+	})
+	return startErr
+}
+
+// localBinaries returns the directory holding bin/pg_ctl of a Postgres
+// installed on this machine, or "" if there is none.
+func localBinaries() string {
+	candidates, _ := filepath.Glob("/usr/lib/postgresql/*/bin/pg_ctl")
+	if path, err := exec.LookPath("pg_ctl"); err == nil {
+		// Debian links pg_ctl on the PATH to a wrapper script, so resolve it
+		// and check that its directory holds the other binaries.
+		if path, err := filepath.EvalSymlinks(path); err == nil {
+			candidates = append([]string{path}, candidates...)
+		}
+	}
+	for _, path := range candidates {
+		dir := filepath.Dir(filepath.Dir(path))
+		if _, err := os.Stat(filepath.Join(dir, "bin", "initdb")); err == nil {
+			return dir
+		}
+	}
+	return ""
+}
+
+func freePort() (uint32, error) {
+	l, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+	return uint32(l.Addr().(*net.TCPAddr).Port), nil
+}
+
+// connect creates an empty schema for t and returns a connection whose
+// search_path is that schema. The schema is dropped when t finishes. t fails
+// if there is no server to connect to, unless SKIP_POSTGRES_TESTS is set.
+func connect(t testing.TB) (context.Context, *pgx.Conn, string) {
+	if err := startServer(); err != nil {
+		noServer(t, err)
+	}
+
+	ctx := context.Background()
+	schema := "guam_test_" + utils.GenerateRandomString(8, "abcdefghijklmnopqrstuvwxyz")
+
+	admin, err := pgx.Connect(ctx, databaseURL)
+	if err != nil {
+		noServer(t, err)
+	}
+	if _, err := admin.Exec(ctx, fmt.Sprintf("CREATE SCHEMA %s", schema)); err != nil {
+		t.Fatal(err)
+	}
+	admin.Close(ctx)
+
+	config, err := pgx.ParseConfig(databaseURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.RuntimeParams["search_path"] = schema
+	conn, err := pgx.ConnectConfig(ctx, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		conn.Close(ctx)
+
+		// conn may have been closed by the test, so drop the schema from a
+		// connection of our own.
+		admin, err := pgx.Connect(ctx, databaseURL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer admin.Close(ctx)
+		if _, err := admin.Exec(ctx, fmt.Sprintf("DROP SCHEMA %s CASCADE", schema)); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	return ctx, conn, schema
+}
+
+// noServer fails t, or skips it if SKIP_POSTGRES_TESTS is set, because err
+// kept it from reaching a server.
+func noServer(t testing.TB, err error) {
+	if os.Getenv("SKIP_POSTGRES_TESTS") != "" {
+		t.Skipf("No postgres server available: %v", err)
+	}
+	t.Fatalf("No postgres server available (set SKIP_POSTGRES_TESTS to skip): %v", err)
+}
+
+// newPool returns a pool connecting like conn, so to the same schema.
+func newPool(t testing.TB, ctx context.Context, conn *pgx.Conn) *pgxpool.Pool {
+	config, err := pgxpool.ParseConfig(databaseURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.ConnConfig = conn.Config().Copy()
+	pool, err := pgxpool.NewWithConfig(ctx, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(pool.Close)
+	return pool
+}
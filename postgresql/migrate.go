@@ -0,0 +1,199 @@
+package postgresql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const defaultMigrationsTable = "guam_migrations"
+
+// Column declares an attribute column added to the user or session table.
+type Column struct {
+	Name string
+	// Type is the SQL type of the column, including any constraints, e.g.
+	// "TEXT NOT NULL DEFAULT ''".
+	Type string
+}
+
+// MigrateOptions configures Migrate.
+type MigrateOptions struct {
+	// MigrationsTable records the applied schema versions of every set of
+	// tables migrated with it, keyed by the table names. It defaults to
+	// guam_migrations.
+	MigrationsTable string
+	// UserAttributes and SessionAttributes are added to the user and session
+	// tables on every run if they don't exist yet.
+	UserAttributes    []Column
+	SessionAttributes []Column
+}
+
+// migrations returns the DDL of every schema version, in order. Version n is
+// migrations(...)[n-1]; never edit or reorder an existing entry. Columns are
+// named as mapped by tables.
+func migrations(tables Tables) [][]string {
+	user := mustEscapeName(tables.User)
+	session := mustEscapeName(tables.Session)
+	key := mustEscapeName(tables.Key)
+	userId := dialect.Column(tables.UserColumns, "id")
+
+	return [][]string{
+		{
+			fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s ( %s TEXT PRIMARY KEY )", user, userId),
+		},
+		{
+			fmt.Sprintf(
+				"CREATE TABLE IF NOT EXISTS %s ( "+
+					"%s TEXT PRIMARY KEY, "+
+					"%s TEXT NOT NULL REFERENCES %s ( %s ) ON DELETE CASCADE, "+
+					"%s BIGINT NOT NULL, "+
+					"%s BIGINT NOT NULL )",
+				session,
+				dialect.Column(tables.SessionColumns, "id"),
+				dialect.Column(tables.SessionColumns, "user_id"),
+				user,
+				userId,
+				dialect.Column(tables.SessionColumns, "active_expires"),
+				dialect.Column(tables.SessionColumns, "idle_expires"),
+			),
+			fmt.Sprintf(
+				"CREATE INDEX IF NOT EXISTS %s ON %s ( %s )",
+				indexName(tables.Session, tables.SessionColumns.Column("user_id")),
+				session,
+				dialect.Column(tables.SessionColumns, "user_id"),
+			),
+		},
+		{
+			fmt.Sprintf(
+				"CREATE TABLE IF NOT EXISTS %s ( "+
+					"%s TEXT PRIMARY KEY, "+
+					"%s TEXT NOT NULL REFERENCES %s ( %s ) ON DELETE CASCADE, "+
+					"%s TEXT )",
+				key,
+				dialect.Column(tables.KeyColumns, "id"),
+				dialect.Column(tables.KeyColumns, "user_id"),
+				user,
+				userId,
+				dialect.Column(tables.KeyColumns, "hashed_password"),
+			),
+			fmt.Sprintf(
+				"CREATE INDEX IF NOT EXISTS %s ON %s ( %s )",
+				indexName(tables.Key, tables.KeyColumns.Column("user_id")),
+				key,
+				dialect.Column(tables.KeyColumns, "user_id"),
+			),
+		},
+	}
+}
+
+// indexName returns the escaped name of an index on column of table. Indexes
+// always live in their table's schema, so any schema prefix is dropped.
+func indexName(table string, column string) string {
+	if i := strings.LastIndex(table, "."); i >= 0 {
+		table = table[i+1:]
+	}
+	return quoteIdentifier(table + "_" + column + "_idx")
+}
+
+// Migrate creates the user, session and key tables and brings them up to the
+// latest schema version, then adds the attribute columns declared in opts.
+// Applied versions are recorded in opts.MigrationsTable for each set of table
+// names, so adapters with different tables can share it. Concurrent calls are
+// serialized with an advisory lock, so it is safe to run on every start.
+func Migrate(ctx context.Context, db DB, tables Tables, opts MigrateOptions) error {
+	if tables.User == "" || tables.Session == "" || tables.Key == "" {
+		return errors.New("postgresql: Migrate requires user, session and key table names")
+	}
+	if opts.MigrationsTable == "" {
+		opts.MigrationsTable = defaultMigrationsTable
+	}
+	escaped := make([]string, 0, 3)
+	for _, table := range []string{tables.User, tables.Session, tables.Key} {
+		name, err := dialect.EscapeName(table)
+		if err != nil {
+			return err
+		}
+		escaped = append(escaped, name)
+	}
+	key := strings.Join(escaped, ", ")
+	migrationsTable, err := dialect.EscapeName(opts.MigrationsTable)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock(hashtext($1))", opts.MigrationsTable); err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf(
+		"CREATE TABLE IF NOT EXISTS %s ( "+
+			"tables TEXT NOT NULL, "+
+			"version INTEGER NOT NULL, "+
+			"applied_at TIMESTAMPTZ NOT NULL DEFAULT now(), "+
+			"PRIMARY KEY ( tables, version ) )",
+		migrationsTable,
+	)
+	if _, err := tx.Exec(ctx, query); err != nil {
+		return err
+	}
+
+	var current int
+	query = fmt.Sprintf("SELECT COALESCE(MAX(version), 0) FROM %s WHERE tables = $1", migrationsTable)
+	if err := tx.QueryRow(ctx, query, key).Scan(&current); err != nil {
+		return err
+	}
+
+	for i, statements := range migrations(tables) {
+		version := i + 1
+		if version <= current {
+			continue
+		}
+		for _, statement := range statements {
+			if _, err := tx.Exec(ctx, statement); err != nil {
+				return fmt.Errorf("postgresql: migration %d: %w", version, err)
+			}
+		}
+		query := fmt.Sprintf("INSERT INTO %s ( tables, version ) VALUES ( $1, $2 )", migrationsTable)
+		if _, err := tx.Exec(ctx, query, key, version); err != nil {
+			return err
+		}
+	}
+
+	if err := addColumns(ctx, tx, tables.User, opts.UserAttributes); err != nil {
+		return err
+	}
+	if err := addColumns(ctx, tx, tables.Session, opts.SessionAttributes); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func addColumns(ctx context.Context, db DB, table string, columns []Column) error {
+	for _, column := range columns {
+		if column.Name == "" || column.Type == "" {
+			return fmt.Errorf("postgresql: attribute column of %s needs a name and a type", table)
+		}
+		name, err := EscapeIdentifier(column.Name)
+		if err != nil {
+			return err
+		}
+		query := fmt.Sprintf(
+			"ALTER TABLE %s ADD COLUMN IF NOT EXISTS %s %s",
+			mustEscapeName(table),
+			name,
+			column.Type,
+		)
+		if _, err := db.Exec(ctx, query); err != nil {
+			return fmt.Errorf("postgresql: adding column %s to %s: %w", column.Name, table, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package postgresql
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/seatedro/guam/auth"
+	"github.com/seatedro/guam/utils"
+)
+
+func TestMigrate(t *testing.T) {
+	t.Parallel()
+
+	ctx, conn, schema := connect(t)
+	tables := Tables{
+		User:    schema + ".auth_user",
+		Session: schema + ".user_session",
+		Key:     schema + ".user_key",
+	}
+
+	opts := MigrateOptions{
+		MigrationsTable: schema + ".guam_migrations",
+		UserAttributes: []Column{
+			{Name: "username", Type: "TEXT NOT NULL"},
+		},
+	}
+
+	// Running twice must be a no-op the second time.
+	for i := 0; i < 2; i++ {
+		if err := Migrate(ctx, conn, tables, opts); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var versions int
+	err := conn.QueryRow(ctx, fmt.Sprintf("SELECT COUNT(*) FROM %s", opts.MigrationsTable)).Scan(&versions)
+	if err != nil || versions != len(migrations(tables)) {
+		t.Fatalf("expected %d versions, got %d: %v", len(migrations(tables)), versions, err)
+	}
+
+	adapter := PostgresAdapter(ctx, conn, tables, false)
+	userId := createUser(t, adapter, true)
+	if err := adapter.SetSession(auth.SessionSchema{
+		ID:            utils.GenerateRandomString(5, ""),
+		UserID:        userId,
+		ActiveExpires: 1,
+		IdleExpires:   1,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Deleting the user cascades to its sessions and keys.
+	if err := adapter.DeleteUser(userId); err != nil {
+		t.Fatal(err)
+	}
+	sessions, err := adapter.GetSessionsByUserId(userId)
+	if err != nil || sessions != nil {
+		t.Fatalf("expected no sessions, got %+v: %v", sessions, err)
+	}
+	keys, err := adapter.GetKeysByUserId(userId)
+	if err != nil || keys != nil {
+		t.Fatalf("expected no keys, got %+v: %v", keys, err)
+	}
+}
+
+func TestMigrateTableSets(t *testing.T) {
+	t.Parallel()
+
+	ctx, conn, _ := connect(t)
+	sets := []Tables{
+		{User: "auth_user", Session: "user_session", Key: "user_key"},
+		{User: "app_user", Session: "app_session", Key: "app_key"},
+	}
+
+	// Both sets share the default migrations table, and each must get every
+	// version.
+	for _, tables := range sets {
+		if err := Migrate(ctx, conn, tables, MigrateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, tables := range sets {
+		for _, table := range []string{tables.User, tables.Session, tables.Key} {
+			var exists bool
+			if err := conn.QueryRow(ctx, "SELECT to_regclass($1) IS NOT NULL", table).Scan(&exists); err != nil || !exists {
+				t.Fatalf("expected %s to exist: %v", table, err)
+			}
+		}
+
+		adapter := PostgresAdapter(ctx, conn, tables, false)
+		if err := adapter.Validate(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var versions int
+	err := conn.QueryRow(ctx, fmt.Sprintf("SELECT COUNT(*) FROM %s", defaultMigrationsTable)).Scan(&versions)
+	if err != nil || versions != 2*len(migrations(sets[0])) {
+		t.Fatalf("expected %d versions, got %d: %v", 2*len(migrations(sets[0])), versions, err)
+	}
+}
+
+func TestMigrateMappedColumns(t *testing.T) {
+	t.Parallel()
+
+	ctx, conn, schema := connect(t)
+	tables := Tables{
+		User:           schema + ".auth_user",
+		Session:        schema + ".user_session",
+		Key:            schema + ".user_key",
+		UserColumns:    Columns{"id": "uid"},
+		SessionColumns: Columns{"user_id": "account_id", "idle_expires": "expires_at_ms"},
+		KeyColumns:     Columns{"user_id": "account_id"},
+	}
+	opts := MigrateOptions{
+		MigrationsTable: schema + ".guam_migrations",
+		UserAttributes:  []Column{{Name: "username", Type: "TEXT"}},
+	}
+	if err := Migrate(ctx, conn, tables, opts); err != nil {
+		t.Fatal(err)
+	}
+
+	adapter := PostgresAdapter(ctx, conn, tables, false)
+	if err := adapter.Validate(ctx); err != nil {
+		t.Fatal(err)
+	}
+	userId := createUser(t, adapter, true)
+	sessionId := utils.GenerateRandomString(5, "")
+	err := adapter.SetSession(auth.SessionSchema{
+		ID:            sessionId,
+		UserID:        userId,
+		ActiveExpires: 1,
+		IdleExpires:   2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := adapter.UpdateSession(sessionId, map[string]any{"idle_expires": 3}); err != nil {
+		t.Fatal(err)
+	}
+
+	session, user, err := adapter.GetSessionAndUser(sessionId)
+	if err != nil || session == nil || user == nil {
+		t.Fatal("expected session and user, got ", err)
+	}
+	if session.UserID != userId || session.IdleExpires != 3 || user.ID != userId || len(session.Attributes) != 0 {
+		t.Fatalf("expected the mapped columns to be read back, got %+v and %+v", session, user)
+	}
+	keys, err := adapter.GetKeysByUserId(userId)
+	if err != nil || len(keys) != 1 || keys[0].UserID != userId {
+		t.Fatalf("expected one key, got %+v: %v", keys, err)
+	}
+	if deleted, err := adapter.DeleteExpiredSessions(ctx, time.UnixMilli(4)); err != nil || deleted != 1 {
+		t.Fatalf("expected the session to expire, got %d: %v", deleted, err)
+	}
+}
@@ -2,154 +2,299 @@ package postgresql
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"strings"
+	"slices"
+	"time"
 
-	"github.com/georgysavva/scany/v2/dbscan"
 	"github.com/georgysavva/scany/v2/pgxscan"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/seatedro/guam-adapters/internal/sqlutil"
 	"github.com/seatedro/guam/auth"
-	"go.uber.org/zap"
 )
 
+const (
+	foreignKeyViolation = "23503"
+	uniqueViolation     = "23505"
+	undefinedColumn     = "42703"
+
+	// expiredSessionsBatchSize bounds the number of rows locked by each
+	// statement of DeleteExpiredSessions.
+	expiredSessionsBatchSize = 1000
+)
+
+// DB is the subset of pgx used by the adapter. It is satisfied by *pgx.Conn,
+// *pgxpool.Pool and pgx.Tx, so a pool can be shared between concurrent
+// requests.
+type DB interface {
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
 var (
-	logger                     *zap.SugaredLogger
-	ESCAPED_USER_TABLE_NAME    string
-	ESCAPED_KEY_TABLE_NAME     string
-	ESCAPED_SESSION_TABLE_NAME string
+	_ DB = (*pgx.Conn)(nil)
+	_ DB = (*pgxpool.Pool)(nil)
+	_ DB = (pgx.Tx)(nil)
 )
 
 type Tables struct {
 	User    string
 	Session string
 	Key     string
+
+	// UserColumns, SessionColumns and KeyColumns rename the columns of the
+	// guam schemas in tables that name them differently, e.g. {"id": "uid"}.
+	// They apply to every query, including those built by Migrate.
+	UserColumns    Columns
+	SessionColumns Columns
+	KeyColumns     Columns
+}
+
+// Columns maps the columns of a guam schema, named after their db tag, to the
+// columns storing them. Columns missing from the map keep their name.
+type Columns = sqlutil.Columns
+
+// Adapter is the guam adapter returned by PostgresAdapter.
+type Adapter interface {
+	auth.AdapterWithGetter
+
+	// WithContext returns a copy of the adapter whose queries run with ctx
+	// instead of the context passed to PostgresAdapter, so that request
+	// cancellation and deadlines reach the database.
+	WithContext(ctx context.Context) Adapter
+
+	// Validate checks that the configured tables exist and have every column
+	// of the guam schemas with a compatible type, the JSON attributes column
+	// as jsonb and the columns declared with WithUserColumns and
+	// WithSessionColumns. It returns a *SchemaError listing all mismatches.
+	Validate(ctx context.Context) error
+
+	// DeleteExpiredSessions deletes, in batches, every session whose idle
+	// period ended before now and returns how many were deleted.
+	DeleteExpiredSessions(ctx context.Context, now time.Time) (int64, error)
+
+	// Prepare prepares the queries of the adapter on conn. They are named
+	// after their SQL, so the adapter uses them whenever it runs on conn
+	// and falls back to unnamed statements elsewhere. With a pool, pass it
+	// as pgxpool.Config.AfterConnect.
+	Prepare(ctx context.Context, conn *pgx.Conn) error
+
+	// RefreshColumns forgets the columns read from the database, so that
+	// getters and the attribute policy read them again, e.g. after a
+	// migration. Columns declared with WithUserColumns and WithSessionColumns
+	// are kept.
+	RefreshColumns()
 }
 
 type postgresAdapterImpl struct {
-	ctx           context.Context
-	db            *pgx.Conn
-	userHelper    HelperFunc[auth.UserSchema]
-	keyHelper     HelperFunc[auth.KeySchema]
-	sessionHelper HelperFunc[auth.SessionSchema]
-	tables        Tables
+	ctx                 context.Context
+	db                  DB
+	logger              logger
+	userHelper          HelperFunc[auth.UserSchema]
+	keyHelper           HelperFunc[auth.KeySchema]
+	sessionHelper       HelperFunc[auth.SessionSchema]
+	tables              Tables
+	escapedUserTable    string
+	escapedKeyTable     string
+	escapedSessionTable string
+	attributePolicy     AttributePolicy
+	userAttributes      *attributeTable
+	sessionAttributes   *attributeTable
+	// jsonAttributes is the jsonb column storing attributes, if any.
+	jsonAttributes        string
+	escapedJSONAttributes string
+	statements            statements
+	selects               *selectCache
+	projection            *Projection
 }
 
+// PostgresAdapter returns an adapter storing users, sessions and keys in db.
+// The table names in tables, like the column names given to options, are part
+// of the program's configuration, so it panics if one can't be escaped; check
+// names coming from elsewhere with ValidateName first.
 func PostgresAdapter(
 	ctx context.Context,
-	db *pgx.Conn,
+	db DB,
 	tables Tables,
 	debugMode bool,
-) auth.AdapterWithGetter {
-	ESCAPED_USER_TABLE_NAME = EscapeName(tables.User)
-	ESCAPED_KEY_TABLE_NAME = EscapeName(tables.Key)
-	ESCAPED_SESSION_TABLE_NAME = EscapeName(tables.Session)
-	if debugMode {
-		l, err := zap.NewDevelopment()
-		if err != nil {
-			logger = zap.NewNop().Sugar()
-		}
-		logger = l.Sugar()
-	} else {
-		l, err := zap.NewProduction(zap.IncreaseLevel(zap.ErrorLevel))
-		if err != nil {
-			logger = zap.NewNop().Sugar()
-		}
-		logger = l.Sugar()
-	}
-
-	userHelper := CreatePreparedStatementHelper[auth.UserSchema](func(index int) string {
-		return fmt.Sprintf("$%d", index+1)
-	})
-	keyHelper := CreatePreparedStatementHelper[auth.KeySchema](func(index int) string {
-		return fmt.Sprintf("$%d", index+1)
-	})
-	sessionHelper := CreatePreparedStatementHelper[auth.SessionSchema](func(index int) string {
+	opts ...Option,
+) Adapter {
+	placeholder := func(index int) string {
 		return fmt.Sprintf("$%d", index+1)
-	})
-	return &postgresAdapterImpl{
-		ctx:           ctx,
-		db:            db,
-		tables:        tables,
-		userHelper:    userHelper,
-		keyHelper:     keyHelper,
-		sessionHelper: sessionHelper,
 	}
+	userHelper := CreateMappedStatementHelper[auth.UserSchema](placeholder, tables.UserColumns)
+	keyHelper := CreateMappedStatementHelper[auth.KeySchema](placeholder, tables.KeyColumns)
+	sessionHelper := CreateMappedStatementHelper[auth.SessionSchema](placeholder, tables.SessionColumns)
+	p := &postgresAdapterImpl{
+		ctx:                 ctx,
+		db:                  db,
+		tables:              tables,
+		userHelper:          userHelper,
+		keyHelper:           keyHelper,
+		sessionHelper:       sessionHelper,
+		escapedUserTable:    mustEscapeName(tables.User),
+		escapedKeyTable:     mustEscapeName(tables.Key),
+		escapedSessionTable: mustEscapeName(tables.Session),
+		selects:             &selectCache{},
+	}
+	p.userAttributes = newAttributeTable[auth.UserSchema](
+		tables.User,
+		p.escapedUserTable,
+		tables.UserColumns,
+	)
+	p.sessionAttributes = newAttributeTable[auth.SessionSchema](
+		tables.Session,
+		p.escapedSessionTable,
+		tables.SessionColumns,
+	)
+	for _, opt := range opts {
+		opt(p)
+	}
+	if p.logger == nil {
+		p.logger = sqlutil.NewLogger(debugMode)
+	}
+	p.statements = p.newStatements()
+	return p
 }
 
-func insertIntoTable(
-	ctx context.Context,
-	tx pgx.Tx,
-	tableName string,
+func (p *postgresAdapterImpl) WithContext(ctx context.Context) Adapter {
+	adapter := *p
+	adapter.ctx = ctx
+	return &adapter
+}
+
+// appendAttributes appends a column, a placeholder and an argument for every
+// attribute. Attribute names come from application code, so names that can't
+// be escaped are rejected. Attributes are sorted by name, so the same keys
+// always give the same SQL and hit pgx's statement cache.
+func appendAttributes(
 	fields []string,
 	placeholders []string,
 	args []any,
-) error {
-	query := fmt.Sprintf(
-		"INSERT INTO %s ( %s ) VALUES ( %s )",
-		tableName,
-		strings.Join(fields, ", "),
-		strings.Join(placeholders, ", "),
-	)
-	_, err := tx.Exec(ctx, query, args...)
-	if err != nil {
+	attributes map[string]any,
+) ([]string, []string, []any, error) {
+	keys := make([]string, 0, len(attributes))
+	for key := range attributes {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	for _, key := range keys {
+		field, err := EscapeIdentifier(key)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		fields = append(fields, field)
+		args = append(args, attributes[key])
+		placeholders = append(placeholders, fmt.Sprintf("$%d", len(args)))
+	}
+	return fields, placeholders, args, nil
+}
+
+// transformKeyError maps constraint violations raised while writing to the key
+// table to their guam equivalents.
+func transformKeyError(err error) error {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
 		return err
 	}
-	return nil
+	switch pgErr.Code {
+	case uniqueViolation:
+		return auth.NewGuamError(auth.AUTH_DUPLICATE_KEY_ID, pgErr.Detail)
+	case foreignKeyViolation:
+		return auth.NewGuamError(auth.AUTH_INVALID_USER_ID, pgErr.Detail)
+	}
+	return err
+}
+
+// transformUserError maps constraint violations raised while writing to the
+// user table to their guam equivalents. guam has no code for a duplicate user
+// id, so unique violations are returned as they are.
+func transformUserError(err error) error {
+	return transformSessionError(err)
+}
+
+// transformSessionError maps constraint violations raised while writing to the
+// session table to their guam equivalents.
+func transformSessionError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == foreignKeyViolation {
+		return auth.NewGuamError(auth.AUTH_INVALID_USER_ID, pgErr.Detail)
+	}
+	return err
 }
 
 func (p *postgresAdapterImpl) GetUser(
 	userId string,
 ) (*auth.UserSchema, error) {
-	var users []auth.UserSchema
-	query := fmt.Sprintf("SELECT * FROM %s WHERE id = $1", ESCAPED_USER_TABLE_NAME)
-	logger.Debugln("Query: ", query)
-	api, err := pgxscan.NewDBScanAPI(dbscan.WithAllowUnknownColumns(true))
+	columns, rows, err := p.querySelect(func(s *selects) string {
+		return s.getUser
+	}, userId)
 	if err != nil {
-		logger.Errorln("Error: ", err)
+		p.logger.Errorln("Error while fetching User: ", err)
 		return nil, err
 	}
-	scan, err := pgxscan.NewAPI(api)
+	p.logger.Debugf("User: %+v\n", sqlutil.RedactedRows{Columns: columns, Rows: rows})
+	if len(rows) == 0 {
+		return nil, nil
+	}
+	user, err := decodeRow[auth.UserSchema](columns, rows[0], p.jsonAttributes)
 	if err != nil {
-		logger.Errorln("Error: ", err)
+		p.logger.Errorln("Error: ", err)
 		return nil, err
 	}
-
-	scan.Select(p.ctx, p.db, &users, query, userId)
-	logger.Debugf("User: %+v\n", users)
-	if users != nil {
-		return &users[0], nil
-	}
-	return nil, nil
+	return &user, nil
 }
 
 func (p *postgresAdapterImpl) SetUser(user auth.UserSchema, key *auth.KeySchema) error {
 	if key == nil {
 		userFields, userPlaceholders, userArgs := p.userHelper(user)
-
-		// If struct has Attributes field, append it to args
-		i := len(userArgs)
-		for key, val := range user.Attributes {
-			userFields = append(userFields, EscapeName(key))
-			userPlaceholders = append(userPlaceholders, fmt.Sprintf("$%d", i+1))
-			userArgs = append(userArgs, val)
-			i++
+		userFields, userPlaceholders, userArgs, err := p.insertAttributes(
+			p.userAttributes,
+			userFields,
+			userPlaceholders,
+			userArgs,
+			user.Attributes,
+		)
+		if err != nil {
+			p.logger.Errorln("Error: ", err)
+			return err
 		}
 
-		query := fmt.Sprintf(
-			"INSERT INTO %s ( %s ) VALUES ( %s )",
-			ESCAPED_USER_TABLE_NAME,
-			strings.Join(userFields, ", "),
-			strings.Join(userPlaceholders, ", "),
+		query := insertStatement(
+			p.statements.insertUser,
+			p.statements.insertUserFields,
+			p.escapedUserTable,
+			userFields,
+			userPlaceholders,
 		)
 
-		_, err := p.db.Exec(p.ctx, query, userArgs...)
+		_, err = p.db.Exec(p.ctx, query, userArgs...)
 		if err != nil {
-			logger.Errorln("Error while inserting into DB: ", err)
-			return err
+			p.logger.Errorln("Error while inserting into DB: ", err)
+			return transformUserError(err)
 		}
+		p.checkColumns(p.userAttributes, user.Attributes)
 		return nil
 	}
 
+	userFields, userPlaceholders, userArgs := p.userHelper(user)
+	userFields, userPlaceholders, userArgs, err := p.insertAttributes(
+		p.userAttributes,
+		userFields,
+		userPlaceholders,
+		userArgs,
+		user.Attributes,
+	)
+	if err != nil {
+		p.logger.Errorln("Error: ", err)
+		return err
+	}
+
 	tx, err := p.db.Begin(p.ctx)
 	if err != nil {
 		return err
@@ -157,36 +302,45 @@ func (p *postgresAdapterImpl) SetUser(user auth.UserSchema, key *auth.KeySchema)
 
 	defer tx.Rollback(p.ctx)
 
-	userFields, userPlaceholders, userArgs := p.userHelper(user)
-
-	// If struct has Attributes field, append it to args
-	i := len(userArgs)
-	for key, val := range user.Attributes {
-		userFields = append(userFields, EscapeName(key))
-		userPlaceholders = append(userPlaceholders, fmt.Sprintf("$%d", i+1))
-		userArgs = append(userArgs, val)
-		i++
-	}
-
-	if err := insertIntoTable(p.ctx, tx, ESCAPED_USER_TABLE_NAME, userFields, userPlaceholders, userArgs); err != nil {
-		return err
+	query := insertStatement(
+		p.statements.insertUser,
+		p.statements.insertUserFields,
+		p.escapedUserTable,
+		userFields,
+		userPlaceholders,
+	)
+	if _, err := tx.Exec(p.ctx, query, userArgs...); err != nil {
+		p.logger.Errorln("Error while inserting into DB: ", err)
+		return transformUserError(err)
 	}
 
 	keyFields, keyPlaceholders, keyArgs := p.keyHelper(*key)
+	query = insertStatement(
+		p.statements.insertKey,
+		p.statements.insertKeyFields,
+		p.escapedKeyTable,
+		keyFields,
+		keyPlaceholders,
+	)
 
-	if err := insertIntoTable(p.ctx, tx, ESCAPED_KEY_TABLE_NAME, keyFields, keyPlaceholders, keyArgs); err != nil {
-		return err
+	if _, err := tx.Exec(p.ctx, query, keyArgs...); err != nil {
+		p.logger.Errorln("Error while inserting into Keys table: ", err)
+		return transformKeyError(err)
 	}
 
-	return tx.Commit(p.ctx)
+	if err := tx.Commit(p.ctx); err != nil {
+		return err
+	}
+	p.checkColumns(p.userAttributes, user.Attributes)
+	return nil
 }
 
 func (p *postgresAdapterImpl) DeleteUser(userId string) error {
-	query := fmt.Sprintf("DELETE FROM %s WHERE id = $1", ESCAPED_USER_TABLE_NAME)
+	query := p.statements.deleteUser
 
 	_, err := p.db.Exec(p.ctx, query, userId)
 	if err != nil {
-		logger.Errorln("Error while deleting user: ", err)
+		p.logger.Errorln("Error while deleting user: ", err)
 		return err
 	}
 	return nil
@@ -196,116 +350,117 @@ func (p *postgresAdapterImpl) UpdateUser(
 	userId string,
 	partialUser map[string]any,
 ) error {
-	var userFields []string
-	var userPlaceholders []string
-	var userArgs []interface{}
-	i := 0
-	for key, value := range partialUser {
-		userFields = append(userFields, EscapeName(key))
-		userPlaceholders = append(userPlaceholders, fmt.Sprintf("$%d", i+1))
-		userArgs = append(userArgs, value)
-		i++
+	userFields, userPlaceholders, userArgs, err := p.updateAttributes(p.userAttributes, partialUser)
+	if err != nil {
+		p.logger.Errorln("Error: ", err)
+		return err
+	}
+	if len(userFields) == 0 {
+		return nil
 	}
 	query := fmt.Sprintf(
-		"UPDATE %s SET %s WHERE id = $%d",
-		ESCAPED_USER_TABLE_NAME,
+		"UPDATE %s SET %s WHERE %s = $%d",
+		p.escapedUserTable,
 		GetSetArgs(userFields, userPlaceholders),
+		dialect.Column(p.tables.UserColumns, "id"),
 		len(userArgs)+1,
 	)
 
-	_, err := p.db.Exec(p.ctx, query, append(userArgs, userId)...)
+	_, err = p.db.Exec(p.ctx, query, append(userArgs, userId)...)
 	if err != nil {
-		logger.Errorln("Error while updating user: ", err)
+		p.logger.Errorln("Error while updating user: ", err)
 		return err
 	}
+	p.checkColumns(p.userAttributes, partialUser)
 	return nil
 }
 
 func (p *postgresAdapterImpl) GetSession(
 	sessionId string,
 ) (*auth.SessionSchema, error) {
-	if ESCAPED_SESSION_TABLE_NAME == "" {
+	if p.tables.Session == "" {
 		return nil, nil
 	}
-	var sessions []auth.SessionSchema
-	query := fmt.Sprintf("SELECT * FROM %s WHERE id = $1", ESCAPED_SESSION_TABLE_NAME)
-	logger.Debugln("Query: ", query)
-	api, err := pgxscan.NewDBScanAPI(dbscan.WithAllowUnknownColumns(true))
+	columns, rows, err := p.querySelect(func(s *selects) string {
+		return s.getSession
+	}, sessionId)
 	if err != nil {
-		logger.Errorln("Error: ", err)
+		p.logger.Errorln("Error while fetching Session: ", err)
 		return nil, err
 	}
-	scan, err := pgxscan.NewAPI(api)
+	p.logger.Debugf("Sessions: %+v\n", sqlutil.RedactedRows{Columns: columns, Rows: rows})
+	if len(rows) == 0 {
+		return nil, nil
+	}
+	session, err := decodeRow[auth.SessionSchema](columns, rows[0], p.jsonAttributes)
 	if err != nil {
-		logger.Errorln("Error: ", err)
+		p.logger.Errorln("Error: ", err)
 		return nil, err
 	}
-
-	scan.Select(p.ctx, p.db, &sessions, query, sessionId)
-	logger.Debugf("Sessions: %+v\n", sessions)
-	if sessions != nil {
-		return &sessions[0], nil
-	}
-	return nil, nil
+	return &session, nil
 }
 
 func (p *postgresAdapterImpl) GetSessionsByUserId(
 	userId string,
 ) ([]auth.SessionSchema, error) {
-	if ESCAPED_SESSION_TABLE_NAME == "" {
+	if p.tables.Session == "" {
 		return nil, nil
 	}
-	var sessions []auth.SessionSchema
-	query := fmt.Sprintf("SELECT * FROM %s WHERE user_id = $1", ESCAPED_SESSION_TABLE_NAME)
-	logger.Debugln("Query: ", query)
-	api, err := pgxscan.NewDBScanAPI(dbscan.WithAllowUnknownColumns(true))
+	columns, rows, err := p.querySelect(func(s *selects) string {
+		return s.getSessionsByUserId
+	}, userId)
 	if err != nil {
-		logger.Errorln("Error: ", err)
+		p.logger.Errorln("Error while fetching Sessions: ", err)
 		return nil, err
 	}
-	scan, err := pgxscan.NewAPI(api)
-	if err != nil {
-		logger.Errorln("Error: ", err)
-		return nil, err
+	p.logger.Debugf("Sessions: %+v\n", sqlutil.RedactedRows{Columns: columns, Rows: rows})
+	if len(rows) == 0 {
+		return nil, nil
 	}
-
-	scan.Select(p.ctx, p.db, &sessions, query, userId)
-	logger.Debugf("Sessions: %+v\n", sessions)
-	if sessions != nil {
-		return sessions, nil
+	sessions := make([]auth.SessionSchema, len(rows))
+	for i, row := range rows {
+		sessions[i], err = decodeRow[auth.SessionSchema](columns, row, p.jsonAttributes)
+		if err != nil {
+			p.logger.Errorln("Error: ", err)
+			return nil, err
+		}
 	}
-	return nil, nil
+	return sessions, nil
 }
 
 func (p *postgresAdapterImpl) SetSession(
 	session auth.SessionSchema,
 ) error {
-	if ESCAPED_SESSION_TABLE_NAME == "" {
+	if p.tables.Session == "" {
 		return nil
 	}
 	sessionFields, sessionPlaceholders, sessionArgs := p.sessionHelper(session)
-
-	// If struct has Attributes field, append it to args
-	i := len(sessionArgs)
-	for key, val := range session.Attributes {
-		sessionFields = append(sessionFields, EscapeName(key))
-		sessionPlaceholders = append(sessionPlaceholders, fmt.Sprintf("$%d", i+1))
-		sessionArgs = append(sessionArgs, val)
-		i++
+	sessionFields, sessionPlaceholders, sessionArgs, err := p.insertAttributes(
+		p.sessionAttributes,
+		sessionFields,
+		sessionPlaceholders,
+		sessionArgs,
+		session.Attributes,
+	)
+	if err != nil {
+		p.logger.Errorln("Error: ", err)
+		return err
 	}
 
-	query := fmt.Sprintf(
-		"INSERT INTO %s ( %s ) VALUES ( %s )",
-		ESCAPED_SESSION_TABLE_NAME,
-		strings.Join(sessionFields, ", "),
-		strings.Join(sessionPlaceholders, ", "),
+	query := insertStatement(
+		p.statements.insertSession,
+		p.statements.insertSessionFields,
+		p.escapedSessionTable,
+		sessionFields,
+		sessionPlaceholders,
 	)
 
-	_, err := p.db.Exec(p.ctx, query, sessionArgs...)
+	_, err = p.db.Exec(p.ctx, query, sessionArgs...)
 	if err != nil {
-		logger.Errorln("Error while inserting into DB: ", err)
-		return err
+		p.logger.Errorln("Error while inserting into DB: ", err)
+		return transformSessionError(err)
 	}
+	p.checkColumns(p.sessionAttributes, session.Attributes)
 
 	return nil
 }
@@ -313,14 +468,14 @@ func (p *postgresAdapterImpl) SetSession(
 func (p *postgresAdapterImpl) DeleteSession(
 	sessionId string,
 ) error {
-	if ESCAPED_SESSION_TABLE_NAME == "" {
+	if p.tables.Session == "" {
 		return nil
 	}
-	query := fmt.Sprintf("DELETE FROM %s WHERE id = $1", ESCAPED_SESSION_TABLE_NAME)
+	query := p.statements.deleteSession
 
 	_, err := p.db.Exec(p.ctx, query, sessionId)
 	if err != nil {
-		logger.Errorln("Error while deleting session: ", err)
+		p.logger.Errorln("Error while deleting session: ", err)
 		return err
 	}
 
@@ -330,60 +485,86 @@ func (p *postgresAdapterImpl) DeleteSession(
 func (p *postgresAdapterImpl) DeleteSessionsByUserId(
 	userId string,
 ) error {
-	if ESCAPED_SESSION_TABLE_NAME == "" {
+	if p.tables.Session == "" {
 		return nil
 	}
-	query := fmt.Sprintf("DELETE FROM %s WHERE user_id = $1", ESCAPED_SESSION_TABLE_NAME)
+	query := p.statements.deleteSessionsByUserId
 
 	_, err := p.db.Exec(p.ctx, query, userId)
 	if err != nil {
-		logger.Errorln("Error while deleting session: ", err)
+		p.logger.Errorln("Error while deleting session: ", err)
 		return err
 	}
 
 	return nil
 }
 
+func (p *postgresAdapterImpl) DeleteExpiredSessions(
+	ctx context.Context,
+	now time.Time,
+) (int64, error) {
+	if p.tables.Session == "" {
+		return 0, nil
+	}
+	query := p.statements.deleteExpiredSessions
+
+	var deleted int64
+	for {
+		tag, err := p.db.Exec(ctx, query, now.UnixMilli(), expiredSessionsBatchSize)
+		if err != nil {
+			p.logger.Errorln("Error while deleting expired sessions: ", err)
+			return deleted, err
+		}
+		deleted += tag.RowsAffected()
+		if tag.RowsAffected() < expiredSessionsBatchSize {
+			return deleted, nil
+		}
+	}
+}
+
 func (p *postgresAdapterImpl) UpdateSession(
 	sessionId string,
 	partialSession map[string]any,
 ) error {
-	if ESCAPED_SESSION_TABLE_NAME == "" {
+	if p.tables.Session == "" {
 		return nil
 	}
-	var sessionFields []string
-	var sessionPlaceholders []string
-	var sessionArgs []interface{}
-	i := 0
-	for key, value := range partialSession {
-		sessionFields = append(sessionFields, EscapeName(key))
-		sessionPlaceholders = append(sessionPlaceholders, fmt.Sprintf("$%d", i+1))
-		sessionArgs = append(sessionArgs, value)
-		i++
+	sessionFields, sessionPlaceholders, sessionArgs, err := p.updateAttributes(p.sessionAttributes, partialSession)
+	if err != nil {
+		p.logger.Errorln("Error: ", err)
+		return err
+	}
+	if len(sessionFields) == 0 {
+		return nil
 	}
 	query := fmt.Sprintf(
-		"UPDATE %s SET %s WHERE id = $%d",
-		ESCAPED_SESSION_TABLE_NAME,
+		"UPDATE %s SET %s WHERE %s = $%d",
+		p.escapedSessionTable,
 		GetSetArgs(sessionFields, sessionPlaceholders),
+		dialect.Column(p.tables.SessionColumns, "id"),
 		len(sessionArgs)+1,
 	)
 
-	_, err := p.db.Exec(p.ctx, query, append(sessionArgs, sessionId)...)
+	_, err = p.db.Exec(p.ctx, query, append(sessionArgs, sessionId)...)
 	if err != nil {
-		logger.Errorln("Error while updating session: ", err)
-		return err
+		p.logger.Errorln("Error while updating session: ", err)
+		return transformSessionError(err)
 	}
+	p.checkColumns(p.sessionAttributes, partialSession)
 	return nil
 }
 
 func (p *postgresAdapterImpl) GetKey(keyId string) (*auth.KeySchema, error) {
 	var keys []auth.KeySchema
-	query := fmt.Sprintf("SELECT * FROM %s WHERE id = $1", ESCAPED_KEY_TABLE_NAME)
+	query := p.statements.getKey
 
-	logger.Debugln("Query: ", query)
-	pgxscan.Select(p.ctx, p.db, &keys, query, keyId)
+	p.logger.Debugln("Query: ", query)
+	if err := pgxscan.Select(p.ctx, p.db, &keys, query, keyId); err != nil {
+		p.logger.Errorln("Error while fetching Key: ", err)
+		return nil, err
+	}
 
-	logger.Debugf("Keys: %+v\n", keys)
+	p.logger.Debugf("Keys: %+v\n", sqlutil.RedactedKeys(keys))
 	if keys != nil {
 		return &keys[0], nil
 	}
@@ -393,69 +574,75 @@ func (p *postgresAdapterImpl) GetKey(keyId string) (*auth.KeySchema, error) {
 
 func (p *postgresAdapterImpl) GetKeysByUserId(userId string) ([]auth.KeySchema, error) {
 	var keys []auth.KeySchema
-	query := fmt.Sprintf("SELECT * FROM %s WHERE user_id = $1", ESCAPED_KEY_TABLE_NAME)
+	query := p.statements.getKeysByUserId
 
-	logger.Debugln("Query: ", query)
-	pgxscan.Select(p.ctx, p.db, &keys, query, userId)
+	p.logger.Debugln("Query: ", query)
+	if err := pgxscan.Select(p.ctx, p.db, &keys, query, userId); err != nil {
+		p.logger.Errorln("Error while fetching Keys: ", err)
+		return nil, err
+	}
 
-	logger.Debugf("Keys: %+v\n", keys)
+	p.logger.Debugf("Keys: %+v\n", sqlutil.RedactedKeys(keys))
 
 	return keys, nil
 }
 
 func (p *postgresAdapterImpl) SetKey(key auth.KeySchema) error {
 	keyFields, keyPlaceholders, keyValues := p.keyHelper(key)
-
-	query := fmt.Sprintf(
-		"INSERT INTO %s ( %s ) VALUES ( %s )",
-		ESCAPED_KEY_TABLE_NAME,
-		strings.Join(keyFields, ", "),
-		strings.Join(keyPlaceholders, ", "),
+	query := insertStatement(
+		p.statements.insertKey,
+		p.statements.insertKeyFields,
+		p.escapedKeyTable,
+		keyFields,
+		keyPlaceholders,
 	)
 
 	_, err := p.db.Exec(p.ctx, query, keyValues...)
 	if err != nil {
-		logger.Errorln("Error while inserting into Keys table: ", err)
-		return err
+		p.logger.Errorln("Error while inserting into Keys table: ", err)
+		return transformKeyError(err)
 	}
 
 	return nil
 }
 
 func (p *postgresAdapterImpl) UpdateKey(keyId string, partialKey map[string]any) error {
-	var keyFields []string
-	var keyPlaceholders []string
-	var keyValues []any
-
-	i := 0
-	for k, v := range partialKey {
-		keyFields = append(keyFields, k)
-		keyPlaceholders = append(keyPlaceholders, fmt.Sprintf("$%d", i+1))
-		keyValues = append(keyValues, v)
+	keyFields, keyPlaceholders, keyValues, err := appendAttributes(
+		nil,
+		nil,
+		nil,
+		p.tables.KeyColumns.Rename(partialKey),
+	)
+	if err != nil {
+		p.logger.Errorln("Error: ", err)
+		return err
+	}
+	if len(keyFields) == 0 {
+		return nil
 	}
-
 	query := fmt.Sprintf(
-		"UPDATE %s SET %s WHERE id = $%d",
-		ESCAPED_KEY_TABLE_NAME,
+		"UPDATE %s SET %s WHERE %s = $%d",
+		p.escapedKeyTable,
 		GetSetArgs(keyFields, keyPlaceholders),
+		dialect.Column(p.tables.KeyColumns, "id"),
 		len(keyFields)+1,
 	)
 
-	_, err := p.db.Exec(p.ctx, query, append(keyValues, keyId)...)
+	_, err = p.db.Exec(p.ctx, query, append(keyValues, keyId)...)
 	if err != nil {
-		logger.Errorln("Error while updating Key table: ", err)
-		return err
+		p.logger.Errorln("Error while updating Key table: ", err)
+		return transformKeyError(err)
 	}
 
 	return nil
 }
 
 func (p *postgresAdapterImpl) DeleteKey(keyId string) error {
-	query := fmt.Sprintf("DELETE FROM %s WHERE id = $1", ESCAPED_KEY_TABLE_NAME)
+	query := p.statements.deleteKey
 
 	_, err := p.db.Exec(p.ctx, query, keyId)
 	if err != nil {
-		logger.Errorln("Error while deleteing from Key table: ", err)
+		p.logger.Errorln("Error while deleteing from Key table: ", err)
 		return err
 	}
 
@@ -463,11 +650,11 @@ func (p *postgresAdapterImpl) DeleteKey(keyId string) error {
 }
 
 func (p *postgresAdapterImpl) DeleteKeysByUserId(userId string) error {
-	query := fmt.Sprintf("DELETE FROM %s WHERE user_id = $1", ESCAPED_KEY_TABLE_NAME)
+	query := p.statements.deleteKeysByUserId
 
 	_, err := p.db.Exec(p.ctx, query, userId)
 	if err != nil {
-		logger.Errorln("Error while deleteing from Key table: ", err)
+		p.logger.Errorln("Error while deleteing from Key table: ", err)
 		return err
 	}
 
@@ -477,46 +664,35 @@ func (p *postgresAdapterImpl) DeleteKeysByUserId(userId string) error {
 func (p *postgresAdapterImpl) GetSessionAndUser(
 	sessionId string,
 ) (*auth.SessionSchema, *auth.UserJoinSessionSchema, error) {
-	if ESCAPED_SESSION_TABLE_NAME == "" {
+	if p.tables.Session == "" {
 		return nil, nil, nil
 	}
 
-	session, err := p.GetSession(sessionId)
+	var userColumnCount int
+	columns, rows, err := p.querySelect(func(s *selects) string {
+		userColumnCount = s.userColumnCount
+		return s.getSessionAndUser
+	}, sessionId)
 	if err != nil {
-		logger.Errorln("Error while fetching Session: ", err)
+		p.logger.Errorln("Error while fetching Session and User: ", err)
 		return nil, nil, err
 	}
 
-	var result []auth.UserJoinSessionSchema
-	query := fmt.Sprintf(
-		"SELECT %s.*, %s.id AS __session_id FROM %s INNER JOIN %s ON %s.id = %s.user_id WHERE %s.id = $1",
-		ESCAPED_USER_TABLE_NAME,
-		ESCAPED_SESSION_TABLE_NAME,
-		ESCAPED_SESSION_TABLE_NAME,
-		ESCAPED_USER_TABLE_NAME,
-		ESCAPED_USER_TABLE_NAME,
-		ESCAPED_SESSION_TABLE_NAME,
-		ESCAPED_SESSION_TABLE_NAME,
-	)
+	p.logger.Debugf("Result: %+v\n", sqlutil.RedactedRows{Columns: columns, Rows: rows})
+	if len(rows) == 0 {
+		return nil, nil, nil
+	}
 
-	logger.Debugln("Query: ", query)
-	api, err := pgxscan.NewDBScanAPI(dbscan.WithAllowUnknownColumns(true))
+	row := rows[0]
+	user, err := decodeRow[auth.UserSchema](columns[:userColumnCount], row[:userColumnCount], p.jsonAttributes)
 	if err != nil {
-		logger.Errorln("Error: ", err)
+		p.logger.Errorln("Error: ", err)
 		return nil, nil, err
 	}
-	scan, err := pgxscan.NewAPI(api)
+	session, err := decodeRow[auth.SessionSchema](columns[userColumnCount:], row[userColumnCount:], p.jsonAttributes)
 	if err != nil {
-		logger.Errorln("Error: ", err)
+		p.logger.Errorln("Error: ", err)
 		return nil, nil, err
 	}
-
-	scan.Select(p.ctx, p.db, &result, query, sessionId)
-
-	logger.Debugf("Result: %+v\n", result[0])
-
-	if result != nil {
-		return session, &result[0], nil
-	}
-	return nil, nil, nil
+	return &session, &auth.UserJoinSessionSchema{UserSchema: user, SessionID: session.ID}, nil
 }
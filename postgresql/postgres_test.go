@@ -5,13 +5,14 @@ package postgresql
 
 import (
 	"context"
-	"log"
+	"errors"
+	"fmt"
 	"math/rand"
-	"os"
+	"sync"
 	"testing"
 
 	"github.com/jackc/pgx/v5"
-	"github.com/joho/godotenv"
+	"github.com/seatedro/guam-adapters/adaptertest"
 	"github.com/seatedro/guam/auth"
 	"github.com/seatedro/guam/utils"
 )
@@ -21,7 +22,7 @@ type User struct {
 	Username string `db:"username"`
 }
 
-func insert(ctx context.Context, conn *pgx.Conn) (string, string, string) {
+func insert(t testing.TB, ctx context.Context, conn *pgx.Conn) (string, string, string) {
 	// Create a new user.
 	userId := utils.GenerateRandomString(5, "")
 	username := utils.GenerateRandomString(6, "")
@@ -32,7 +33,7 @@ func insert(ctx context.Context, conn *pgx.Conn) (string, string, string) {
 		username,
 	)
 	if err != nil {
-		log.Fatal(err)
+		t.Fatal(err)
 	}
 
 	// Create a new session.
@@ -48,7 +49,7 @@ func insert(ctx context.Context, conn *pgx.Conn) (string, string, string) {
 		idle_expires,
 	)
 	if err != nil {
-		log.Fatal(err)
+		t.Fatal(err)
 	}
 
 	// Create a new key.
@@ -62,13 +63,13 @@ func insert(ctx context.Context, conn *pgx.Conn) (string, string, string) {
 		hashedPassword,
 	)
 	if err != nil {
-		log.Fatal(err)
+		t.Fatal(err)
 	}
 
 	return userId, sessionId, keyId
 }
 
-func getAdapter(ctx context.Context, conn *pgx.Conn) auth.AdapterWithGetter {
+func getAdapter(ctx context.Context, conn *pgx.Conn) Adapter {
 	return PostgresAdapter(ctx, conn, Tables{
 		User:    "auth_user",
 		Session: "user_session",
@@ -76,60 +77,39 @@ func getAdapter(ctx context.Context, conn *pgx.Conn) auth.AdapterWithGetter {
 	}, false)
 }
 
-func delete(ctx context.Context, conn *pgx.Conn) {
-	// Delete all rows from the tables.
+// setup migrates a schema of t's own and returns an adapter using it.
+func setup(t testing.TB) (context.Context, *pgx.Conn, Adapter) {
+	ctx, conn, _ := connect(t)
 
-	_, err := conn.Exec(context.Background(), "DELETE FROM user_session")
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	_, err = conn.Exec(context.Background(), "DELETE FROM user_key")
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	_, err = conn.Exec(context.Background(), "DELETE FROM auth_user")
-	if err != nil {
-		log.Fatal(err)
-	}
-}
-
-func setup() (context.Context, *pgx.Conn, auth.AdapterWithGetter) {
-	err := godotenv.Load()
-	if err != nil {
-		log.Fatal("Error loading .env file")
-	}
-
-	// Connect to the "postgres" database.
-	ctx := context.Background()
-	conn, err := pgx.Connect(ctx, os.Getenv("DATABASE_URL"))
+	adapter := getAdapter(ctx, conn)
+	err := Migrate(ctx, conn, Tables{
+		User:    "auth_user",
+		Session: "user_session",
+		Key:     "user_key",
+	}, MigrateOptions{
+		UserAttributes: []Column{{Name: "username", Type: "TEXT"}},
+	})
 	if err != nil {
-		log.Fatal(err)
+		t.Fatal(err)
 	}
 
-	// Create a new adapter.
-	adapter := getAdapter(ctx, conn)
-
 	return ctx, conn, adapter
 }
 
 func TestGetUser(t *testing.T) {
-	ctx, conn, adapter := setup()
-	userId, _, _ := insert(ctx, conn)
+	t.Parallel()
 
-	defer conn.Close(ctx)
+	ctx, conn, adapter := setup(t)
+	userId, _, _ := insert(t, ctx, conn)
 
 	// Get the user.
 	_, err := adapter.GetUser(userId)
 	if err != nil {
-		log.Fatal(err)
+		t.Fatal(err)
 	}
-
-	delete(ctx, conn)
 }
 
-func createUser(adapter auth.AdapterWithGetter, withKey bool) string {
+func createUser(t testing.TB, adapter auth.AdapterWithGetter, withKey bool) string {
 	// Set the user.
 	var key *auth.KeySchema = nil
 	userId := utils.GenerateRandomString(5, "")
@@ -150,55 +130,53 @@ func createUser(adapter auth.AdapterWithGetter, withKey bool) string {
 	}
 	err := adapter.SetUser(user, key)
 	if err != nil {
-		log.Fatal(err)
+		t.Fatal(err)
 	}
 
 	return userId
 }
 
 func TestSetUser(t *testing.T) {
-	ctx, conn, adapter := setup()
-	defer conn.Close(ctx)
+	t.Parallel()
 
-	_ = createUser(adapter, false)
+	_, _, adapter := setup(t)
 
-	delete(ctx, conn)
+	_ = createUser(t, adapter, false)
 }
 
 func TestSetUserWithKey(t *testing.T) {
-	ctx, conn, adapter := setup()
-	defer conn.Close(ctx)
+	t.Parallel()
 
-	_ = createUser(adapter, true)
+	_, _, adapter := setup(t)
 
-	delete(ctx, conn)
+	_ = createUser(t, adapter, true)
 }
 
 func TestDeleteUser(t *testing.T) {
-	ctx, conn, adapter := setup()
-	defer conn.Close(ctx)
+	t.Parallel()
+
+	_, _, adapter := setup(t)
 
-	userId := createUser(adapter, false)
+	userId := createUser(t, adapter, false)
 	// Delete the user.
 	err := adapter.DeleteUser(userId)
 	if err != nil {
-		log.Fatal(err)
+		t.Fatal(err)
 	}
 
 	// Try to get the user.
 	user, err := adapter.GetUser(userId)
 	if err != nil || user != nil {
-		log.Fatal(err)
+		t.Fatal(err)
 	}
-
-	delete(ctx, conn)
 }
 
 func TestUpdateUser(t *testing.T) {
-	ctx, conn, adapter := setup()
-	defer conn.Close(ctx)
+	t.Parallel()
+
+	_, _, adapter := setup(t)
 
-	userId := createUser(adapter, false)
+	userId := createUser(t, adapter, false)
 
 	// Update the user.
 	// Username: utils.GenerateRandomString(5, ""),
@@ -208,46 +186,40 @@ func TestUpdateUser(t *testing.T) {
 
 	err := adapter.UpdateUser(userId, partialUser)
 	if err != nil {
-		log.Fatal(err)
+		t.Fatal(err)
 	}
-
-	delete(ctx, conn)
 }
 
 func TestGetSession(t *testing.T) {
-	ctx, conn, adapter := setup()
+	t.Parallel()
 
-	defer conn.Close(ctx)
+	ctx, conn, adapter := setup(t)
 
-	_, sessionId, _ := insert(ctx, conn)
+	_, sessionId, _ := insert(t, ctx, conn)
 
 	// Get the session.
 	_, err := adapter.GetSession(sessionId)
 	if err != nil {
-		log.Fatal(err)
+		t.Fatal(err)
 	}
-
-	delete(ctx, conn)
 }
 
 func TestGetSessionsByUserId(t *testing.T) {
-	ctx, conn, adapter := setup()
+	t.Parallel()
 
-	defer conn.Close(ctx)
+	ctx, conn, adapter := setup(t)
 
-	userId, _, _ := insert(ctx, conn)
+	userId, _, _ := insert(t, ctx, conn)
 
 	// Get the session.
 	_, err := adapter.GetSessionsByUserId(userId)
 	if err != nil {
-		log.Fatal(err)
+		t.Fatal(err)
 	}
-
-	delete(ctx, conn)
 }
 
-func createSession(adapter auth.AdapterWithGetter) string {
-	userId := createUser(adapter, true)
+func createSession(t testing.TB, adapter auth.AdapterWithGetter) string {
+	userId := createUser(t, adapter, true)
 
 	// Set the session.
 	sessionId := utils.GenerateRandomString(5, "")
@@ -260,69 +232,65 @@ func createSession(adapter auth.AdapterWithGetter) string {
 
 	err := adapter.SetSession(session)
 	if err != nil {
-		log.Fatal(err)
+		t.Fatal(err)
 	}
 
 	return sessionId
 }
 
 func TestSetSession(t *testing.T) {
-	ctx, conn, adapter := setup()
-
-	defer conn.Close(ctx)
+	t.Parallel()
 
-	_ = createSession(adapter)
+	_, _, adapter := setup(t)
 
-	delete(ctx, conn)
+	_ = createSession(t, adapter)
 }
 
 func TestDeleteSession(t *testing.T) {
-	ctx, conn, adapter := setup()
+	t.Parallel()
 
-	defer conn.Close(ctx)
+	ctx, conn, adapter := setup(t)
 
-	_, sessionId, _ := insert(ctx, conn)
+	_, sessionId, _ := insert(t, ctx, conn)
 
 	// Delete the session.
 	err := adapter.DeleteSession(sessionId)
 	if err != nil {
-		log.Fatal(err)
+		t.Fatal(err)
 	}
 
 	// Try to get the session.
 	session, err := adapter.GetSession(sessionId)
 	if err != nil || session != nil {
-		log.Fatal(err)
+		t.Fatal(err)
 	}
-
-	delete(ctx, conn)
 }
 
 func TestDeleteSessionsByUserId(t *testing.T) {
-	ctx, conn, adapter := setup()
+	t.Parallel()
 
-	defer conn.Close(ctx)
+	ctx, conn, adapter := setup(t)
 
-	userId, _, _ := insert(ctx, conn)
+	userId, _, _ := insert(t, ctx, conn)
 
 	// Delete the session.
 	err := adapter.DeleteSessionsByUserId(userId)
 	if err != nil {
-		log.Fatal(err)
+		t.Fatal(err)
 	}
 	// Try to get the session.
 	session, err := adapter.GetSessionsByUserId(userId)
 	if err != nil || session != nil {
-		log.Fatal(err)
+		t.Fatal(err)
 	}
-	delete(ctx, conn)
 }
 
 func TestUpdateSession(t *testing.T) {
-	ctx, conn, adapter := setup()
-	defer conn.Close(ctx)
+	t.Parallel()
+
+	_, _, adapter := setup(t)
 
-	sessionId := createSession(adapter)
+	sessionId := createSession(t, adapter)
 
 	// Update the session.
 	partialSession := map[string]interface{}{
@@ -331,45 +299,40 @@ func TestUpdateSession(t *testing.T) {
 	}
 	err := adapter.UpdateSession(sessionId, partialSession)
 	if err != nil {
-		log.Fatal(err)
+		t.Fatal(err)
 	}
-	delete(ctx, conn)
 }
 
 func TestGetKey(t *testing.T) {
-	ctx, conn, adapter := setup()
+	t.Parallel()
 
-	defer conn.Close(ctx)
+	ctx, conn, adapter := setup(t)
 
-	_, _, keyId := insert(ctx, conn)
+	_, _, keyId := insert(t, ctx, conn)
 
 	// Get the key.
 	_, err := adapter.GetKey(keyId)
 	if err != nil {
-		log.Fatal(err)
+		t.Fatal(err)
 	}
-
-	delete(ctx, conn)
 }
 
 func TestGetKeysByUserId(t *testing.T) {
-	ctx, conn, adapter := setup()
+	t.Parallel()
 
-	defer conn.Close(ctx)
+	ctx, conn, adapter := setup(t)
 
-	userId, _, _ := insert(ctx, conn)
+	userId, _, _ := insert(t, ctx, conn)
 
 	// Get the session.
 	_, err := adapter.GetKeysByUserId(userId)
 	if err != nil {
-		log.Fatal(err)
+		t.Fatal(err)
 	}
-
-	delete(ctx, conn)
 }
 
-func createKey(adapter auth.AdapterWithGetter) string {
-	userId := createUser(adapter, true)
+func createKey(t testing.TB, adapter auth.AdapterWithGetter) string {
+	userId := createUser(t, adapter, true)
 
 	// Set the key.
 	keyId := utils.GenerateRandomString(5, "")
@@ -382,69 +345,65 @@ func createKey(adapter auth.AdapterWithGetter) string {
 
 	err := adapter.SetKey(key)
 	if err != nil {
-		log.Fatal(err)
+		t.Fatal(err)
 	}
 
 	return keyId
 }
 
 func TestSetKey(t *testing.T) {
-	ctx, conn, adapter := setup()
-
-	defer conn.Close(ctx)
+	t.Parallel()
 
-	_ = createKey(adapter)
+	_, _, adapter := setup(t)
 
-	delete(ctx, conn)
+	_ = createKey(t, adapter)
 }
 
 func TestDeleteKey(t *testing.T) {
-	ctx, conn, adapter := setup()
+	t.Parallel()
 
-	defer conn.Close(ctx)
+	ctx, conn, adapter := setup(t)
 
-	_, _, keyId := insert(ctx, conn)
+	_, _, keyId := insert(t, ctx, conn)
 
 	// Delete the key.
 	err := adapter.DeleteKey(keyId)
 	if err != nil {
-		log.Fatal(err)
+		t.Fatal(err)
 	}
 
 	// Try to get the key.
 	key, err := adapter.GetKey(keyId)
 	if err != nil || key != nil {
-		log.Fatal(err)
+		t.Fatal(err)
 	}
-
-	delete(ctx, conn)
 }
 
 func TestDeleteKeysByUserId(t *testing.T) {
-	ctx, conn, adapter := setup()
+	t.Parallel()
 
-	defer conn.Close(ctx)
+	ctx, conn, adapter := setup(t)
 
-	userId, _, _ := insert(ctx, conn)
+	userId, _, _ := insert(t, ctx, conn)
 
 	// Delete the session.
 	err := adapter.DeleteKeysByUserId(userId)
 	if err != nil {
-		log.Fatal(err)
+		t.Fatal(err)
 	}
 	// Try to get the key.
 	key, err := adapter.GetKeysByUserId(userId)
 	if err != nil || key != nil {
-		log.Fatal(err)
+		t.Fatal(err)
 	}
-	delete(ctx, conn)
 }
 
 func TestUpdateKey(t *testing.T) {
-	ctx, conn, adapter := setup()
-	defer conn.Close(ctx)
+	t.Parallel()
+
+	_, _, adapter := setup(t)
 
-	keyId := createKey(adapter)
+	keyId := createKey(t, adapter)
 
 	// Update the key.
 	hashedPassword := utils.GenerateScryptHash(utils.GenerateRandomString(10, ""))
@@ -453,21 +412,378 @@ func TestUpdateKey(t *testing.T) {
 	}
 	err := adapter.UpdateKey(keyId, partialKey)
 	if err != nil {
-		log.Fatal(err)
+		t.Fatal(err)
+	}
+}
+
+func TestUpdateKeyEmpty(t *testing.T) {
+	t.Parallel()
+
+	_, _, adapter := setup(t)
+
+	keyId := createKey(t, adapter)
+
+	if err := adapter.UpdateKey(keyId, map[string]interface{}{}); err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := adapter.GetKey(keyId)
+	if err != nil || key == nil {
+		t.Fatal("expected key to be kept: ", err)
 	}
-	delete(ctx, conn)
 }
 
 func TestGetSessionAndUser(t *testing.T) {
-	ctx, conn, adapter := setup()
-	defer conn.Close(ctx)
+	t.Parallel()
+
+	ctx, conn, adapter := setup(t)
+
+	_, sessionId, _ := insert(t, ctx, conn)
 
-	sessionId := createSession(adapter)
+	expected, err := adapter.GetSession(sessionId)
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	session, user, err := adapter.GetSessionAndUser(sessionId)
-	if err != nil || sessionId != session.ID || session.UserID != user.ID {
-		log.Fatal(err)
+	if err != nil ||
+		session.ID != expected.ID ||
+		session.UserID != expected.UserID ||
+		session.ActiveExpires != expected.ActiveExpires ||
+		session.IdleExpires != expected.IdleExpires ||
+		session.UserID != user.ID {
+		t.Fatalf("expected %+v, got %+v %+v: %v", expected, session, user, err)
+	}
+}
+
+func expectGuamError(t testing.TB, err error, message auth.ErrorMessage) {
+	var guamErr *auth.GuamError
+	if !errors.As(err, &guamErr) || guamErr.Message != message {
+		t.Fatalf("expected %s, got %v", message, err)
+	}
+}
+
+func TestSetKeyDuplicateKeyId(t *testing.T) {
+	t.Parallel()
+
+	ctx, conn, adapter := setup(t)
+
+	userId, _, keyId := insert(t, ctx, conn)
+
+	hashedPassword := utils.GenerateScryptHash(utils.GenerateRandomString(6, ""))
+	err := adapter.SetKey(auth.KeySchema{
+		ID:             keyId,
+		UserID:         userId,
+		HashedPassword: &hashedPassword,
+	})
+	expectGuamError(t, err, auth.AUTH_DUPLICATE_KEY_ID)
+}
+
+func TestSetKeyInvalidUserId(t *testing.T) {
+	t.Parallel()
+
+	_, _, adapter := setup(t)
+
+	err := adapter.SetKey(auth.KeySchema{
+		ID:     utils.GenerateRandomString(5, ""),
+		UserID: utils.GenerateRandomString(5, ""),
+	})
+	expectGuamError(t, err, auth.AUTH_INVALID_USER_ID)
+}
+
+func TestSetUserWithDuplicateKeyId(t *testing.T) {
+	t.Parallel()
+
+	ctx, conn, adapter := setup(t)
+
+	_, _, keyId := insert(t, ctx, conn)
+
+	userId := utils.GenerateRandomString(5, "")
+	err := adapter.SetUser(auth.UserSchema{
+		ID: userId,
+		Attributes: map[string]interface{}{
+			"username": utils.GenerateRandomString(6, ""),
+		},
+	}, &auth.KeySchema{
+		ID:     keyId,
+		UserID: userId,
+	})
+	expectGuamError(t, err, auth.AUTH_DUPLICATE_KEY_ID)
+
+	// The user insert must have been rolled back with the key.
+	user, err := adapter.GetUser(userId)
+	if err != nil || user != nil {
+		t.Fatal("expected user to be rolled back: ", err)
+	}
+}
+
+func TestSetSessionInvalidUserId(t *testing.T) {
+	t.Parallel()
+
+	_, _, adapter := setup(t)
+
+	err := adapter.SetSession(auth.SessionSchema{
+		ID:            utils.GenerateRandomString(5, ""),
+		UserID:        utils.GenerateRandomString(5, ""),
+		ActiveExpires: rand.Int63n(1000000000000),
+		IdleExpires:   rand.Int63n(1000000000000),
+	})
+	expectGuamError(t, err, auth.AUTH_INVALID_USER_ID)
+}
+
+func TestUpdateSessionInvalidUserId(t *testing.T) {
+	t.Parallel()
+
+	ctx, conn, adapter := setup(t)
+
+	_, sessionId, _ := insert(t, ctx, conn)
+
+	err := adapter.UpdateSession(sessionId, map[string]any{
+		"user_id": utils.GenerateRandomString(5, ""),
+	})
+	expectGuamError(t, err, auth.AUTH_INVALID_USER_ID)
+}
+
+func TestUpdateKeyInvalidUserId(t *testing.T) {
+	t.Parallel()
+
+	ctx, conn, adapter := setup(t)
+
+	_, _, keyId := insert(t, ctx, conn)
+
+	err := adapter.UpdateKey(keyId, map[string]any{
+		"user_id": utils.GenerateRandomString(5, ""),
+	})
+	expectGuamError(t, err, auth.AUTH_INVALID_USER_ID)
+}
+
+func TestUpdateKeyDuplicateKeyId(t *testing.T) {
+	t.Parallel()
+
+	ctx, conn, adapter := setup(t)
+
+	_, _, keyId := insert(t, ctx, conn)
+	_, _, otherKeyId := insert(t, ctx, conn)
+
+	err := adapter.UpdateKey(keyId, map[string]any{"id": otherKeyId})
+	expectGuamError(t, err, auth.AUTH_DUPLICATE_KEY_ID)
+}
+
+func TestPoolConcurrentSetSession(t *testing.T) {
+	t.Parallel()
+
+	ctx, conn, _ := setup(t)
+
+	adapter := PostgresAdapter(ctx, newPool(t, ctx, conn), Tables{
+		User:    "auth_user",
+		Session: "user_session",
+		Key:     "user_key",
+	}, false)
+	userId := createUser(t, adapter, true)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- adapter.SetSession(auth.SessionSchema{
+				ID:            utils.GenerateRandomString(10, ""),
+				UserID:        userId,
+				ActiveExpires: rand.Int63n(1000000000000),
+				IdleExpires:   rand.Int63n(1000000000000),
+			})
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
 	}
 
-	delete(ctx, conn)
+	sessions, err := adapter.GetSessionsByUserId(userId)
+	if err != nil || len(sessions) != 20 {
+		t.Fatalf("expected 20 sessions, got %d: %v", len(sessions), err)
+	}
+}
+
+func TestAdaptersAreIsolated(t *testing.T) {
+	t.Parallel()
+
+	ctx, conn, _ := setup(t)
+	pool := newPool(t, ctx, conn)
+
+	tables := make([]Tables, 8)
+	for i := range tables {
+		tables[i] = Tables{
+			User:    fmt.Sprintf("auth_user_%d", i),
+			Session: fmt.Sprintf("user_session_%d", i),
+			Key:     fmt.Sprintf("user_key_%d", i),
+		}
+		err := Migrate(ctx, conn, tables[i], MigrateOptions{
+			UserAttributes: []Column{{Name: "username", Type: "TEXT"}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// setup already migrated the unprefixed tables, so make sure these
+		// were created rather than taken to be up to date.
+		for _, table := range []string{tables[i].User, tables[i].Session, tables[i].Key} {
+			var exists bool
+			err := conn.QueryRow(ctx, "SELECT to_regclass($1) IS NOT NULL", table).Scan(&exists)
+			if err != nil || !exists {
+				t.Fatalf("expected %s to exist: %v", table, err)
+			}
+		}
+	}
+
+	// Create the adapters and write through them concurrently.
+	adapters := make([]Adapter, len(tables))
+	userIds := make([]string, len(tables))
+	errs := make([]error, len(tables))
+	var wg sync.WaitGroup
+	for i := range tables {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			adapters[i] = PostgresAdapter(ctx, pool, tables[i], false)
+			userIds[i] = utils.GenerateRandomString(5, "")
+			errs[i] = adapters[i].SetUser(auth.UserSchema{
+				ID:         userIds[i],
+				Attributes: map[string]any{"username": utils.GenerateRandomString(6, "")},
+			}, &auth.KeySchema{ID: utils.GenerateRandomString(5, ""), UserID: userIds[i]})
+		}(i)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Each adapter only sees what was written through it.
+	for i, adapter := range adapters {
+		for j, userId := range userIds {
+			user, err := adapter.GetUser(userId)
+			if err != nil {
+				t.Fatal(err)
+			}
+			keys, err := adapter.GetKeysByUserId(userId)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if i == j && (user == nil || len(keys) != 1) {
+				t.Fatalf("expected adapter %d to find its user, got %+v and %d keys", i, user, len(keys))
+			}
+			if i != j && (user != nil || len(keys) != 0) {
+				t.Fatalf("expected adapter %d not to see the user of adapter %d, got %+v and %d keys", i, j, user, len(keys))
+			}
+		}
+
+		var count int
+		err := conn.QueryRow(ctx, fmt.Sprintf("SELECT COUNT(*) FROM %s", tables[i].User)).Scan(&count)
+		if err != nil || count != 1 {
+			t.Fatalf("expected one user in %s, got %d: %v", tables[i].User, count, err)
+		}
+	}
+}
+
+func TestWithContextCancelled(t *testing.T) {
+	t.Parallel()
+
+	ctx, conn, adapter := setup(t)
+
+	userId, _, _ := insert(t, ctx, conn)
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+
+	err := adapter.WithContext(cancelled).DeleteUser(userId)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected %v, got %v", context.Canceled, err)
+	}
+
+	// The original adapter is unaffected by the derived one.
+	user, err := adapter.GetUser(userId)
+	if err != nil || user == nil {
+		t.Fatal("expected user to still exist: ", err)
+	}
+}
+
+func TestGetSessionAndUserMissingSession(t *testing.T) {
+	t.Parallel()
+
+	_, _, adapter := setup(t)
+
+	session, user, err := adapter.GetSessionAndUser(utils.GenerateRandomString(5, ""))
+	if err != nil || session != nil || user != nil {
+		t.Fatalf("expected no session and user, got %+v %+v: %v", session, user, err)
+	}
+}
+
+func TestGettersReturnErrorOnClosedConnection(t *testing.T) {
+	t.Parallel()
+
+	ctx, conn, adapter := setup(t)
+
+	userId, sessionId, keyId := insert(t, ctx, conn)
+	conn.Close(ctx)
+
+	if _, err := adapter.GetUser(userId); err == nil {
+		t.Fatal("expected GetUser to fail")
+	}
+	if _, err := adapter.GetSession(sessionId); err == nil {
+		t.Fatal("expected GetSession to fail")
+	}
+	if _, err := adapter.GetSessionsByUserId(userId); err == nil {
+		t.Fatal("expected GetSessionsByUserId to fail")
+	}
+	if _, err := adapter.GetKey(keyId); err == nil {
+		t.Fatal("expected GetKey to fail")
+	}
+	if _, err := adapter.GetKeysByUserId(userId); err == nil {
+		t.Fatal("expected GetKeysByUserId to fail")
+	}
+	if _, _, err := adapter.GetSessionAndUser(sessionId); err == nil {
+		t.Fatal("expected GetSessionAndUser to fail")
+	}
+}
+
+func TestGettersReturnErrorOnMismatchedSchema(t *testing.T) {
+	t.Parallel()
+
+	ctx, conn, _ := setup(t)
+
+	// The session and key tables are swapped, so reading either must fail
+	// instead of looking like "not found".
+	adapter := PostgresAdapter(ctx, conn, Tables{
+		User:    "auth_user",
+		Session: "user_key",
+		Key:     "user_session",
+	}, false)
+
+	userId, sessionId, keyId := insert(t, ctx, conn)
+
+	if _, err := adapter.GetKey(sessionId); err == nil {
+		t.Fatal("expected GetKey to fail")
+	}
+	if _, err := adapter.GetKeysByUserId(userId); err == nil {
+		t.Fatal("expected GetKeysByUserId to fail")
+	}
+	if _, _, err := adapter.GetSessionAndUser(keyId); err == nil {
+		t.Fatal("expected GetSessionAndUser to fail")
+	}
+}
+
+func TestConformance(t *testing.T) {
+	adaptertest.Run(t, func(t *testing.T) auth.AdapterWithGetter {
+		ctx, conn, adapter := setup(t)
+		if _, err := conn.Exec(ctx, "ALTER TABLE user_session ADD COLUMN country TEXT"); err != nil {
+			t.Fatal(err)
+		}
+		return adapter
+	})
 }
@@ -0,0 +1,63 @@
+package postgresql
+
+import (
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/seatedro/guam-adapters/internal/sqlutil"
+)
+
+// queryRows runs query and returns the names of its columns and the values of
+// every row, as decoded by pgx.
+func (p *postgresAdapterImpl) queryRows(query string, args ...any) ([]string, [][]any, error) {
+	rows, err := p.db.Query(p.ctx, query, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	var columns []string
+	for _, field := range rows.FieldDescriptions() {
+		columns = append(columns, field.Name)
+	}
+
+	var values [][]any
+	for rows.Next() {
+		row, err := rows.Values()
+		if err != nil {
+			return nil, nil, err
+		}
+		for i, val := range row {
+			row[i] = normalizeValue(val)
+		}
+		values = append(values, row)
+	}
+	return columns, values, rows.Err()
+}
+
+// normalizeValue converts the values pgx decodes to its own types into the
+// closest plain Go type.
+func normalizeValue(val any) any {
+	switch val := val.(type) {
+	case pgtype.Numeric:
+		f, err := val.Float64Value()
+		if err != nil || !f.Valid {
+			return nil
+		}
+		return f.Float64
+	case [16]byte:
+		return fmt.Sprintf("%x-%x-%x-%x-%x", val[0:4], val[4:6], val[6:8], val[8:10], val[10:16])
+	}
+	return val
+}
+
+// decodeRow sets the fields of a T from the columns named by their db tag and
+// collects every other column into its Attributes field. If jsonAttributes is
+// set, Attributes is read from that column instead.
+func decodeRow[T any](columns []string, values []any, jsonAttributes string) (T, error) {
+	row, err := sqlutil.DecodeRow[T](columns, values, jsonAttributes)
+	if err != nil {
+		return row, fmt.Errorf("postgresql: %w", err)
+	}
+	return row, nil
+}
@@ -0,0 +1,111 @@
+package postgresql
+
+import (
+	"testing"
+	"time"
+
+	"github.com/seatedro/guam/auth"
+	"github.com/seatedro/guam/utils"
+)
+
+func TestDecodeRow(t *testing.T) {
+	t.Parallel()
+
+	columns := []string{"id", "user_id", "active_expires", "idle_expires", "country", "ip"}
+	values := []any{"session", "user", int32(1), int64(2), "NZ", nil}
+	session, err := decodeRow[auth.SessionSchema](columns, values, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if session.ID != "session" || session.UserID != "user" || session.ActiveExpires != 1 || session.IdleExpires != 2 {
+		t.Fatalf("unexpected session %+v", session)
+	}
+	if len(session.Attributes) != 2 || session.Attributes["country"] != "NZ" || session.Attributes["ip"] != nil {
+		t.Fatalf("unexpected attributes %v", session.Attributes)
+	}
+
+	if _, err := decodeRow[auth.SessionSchema](columns, []any{1.5, "user", 1, 2, nil, nil}, ""); err == nil {
+		t.Fatal("expected an error for a float id")
+	}
+}
+
+func TestAttributeTypes(t *testing.T) {
+	t.Parallel()
+
+	ctx, conn, _ := connect(t)
+	tables := Tables{
+		User:    "auth_user",
+		Session: "user_session",
+		Key:     "user_key",
+	}
+	err := Migrate(ctx, conn, tables, MigrateOptions{
+		UserAttributes: []Column{
+			{Name: "username", Type: "TEXT"},
+			{Name: "age", Type: "INTEGER"},
+			{Name: "admin", Type: "BOOLEAN"},
+			{Name: "created_at", Type: "TIMESTAMPTZ"},
+		},
+		SessionAttributes: []Column{{Name: "country", Type: "TEXT"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	adapter := PostgresAdapter(ctx, conn, tables, false)
+
+	createdAt := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+	userId := utils.GenerateRandomString(5, "")
+	err = adapter.SetUser(auth.UserSchema{
+		ID: userId,
+		Attributes: map[string]any{
+			"username":   "guam",
+			"age":        30,
+			"admin":      true,
+			"created_at": createdAt,
+		},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	user, err := adapter.GetUser(userId)
+	if err != nil || user == nil {
+		t.Fatal("expected user, got ", err)
+	}
+	if user.Attributes["username"] != "guam" ||
+		user.Attributes["age"] != int32(30) ||
+		user.Attributes["admin"] != true ||
+		!createdAt.Equal(user.Attributes["created_at"].(time.Time)) {
+		t.Fatalf("unexpected attributes %#v", user.Attributes)
+	}
+
+	sessionId := utils.GenerateRandomString(5, "")
+	err = adapter.SetSession(auth.SessionSchema{
+		ID:            sessionId,
+		UserID:        userId,
+		ActiveExpires: 1,
+		IdleExpires:   2,
+		Attributes:    map[string]any{"country": "NZ"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sessions, err := adapter.GetSessionsByUserId(userId)
+	if err != nil || len(sessions) != 1 || sessions[0].Attributes["country"] != "NZ" {
+		t.Fatalf("unexpected sessions %+v: %v", sessions, err)
+	}
+
+	session, joined, err := adapter.GetSessionAndUser(sessionId)
+	if err != nil || session == nil || joined == nil {
+		t.Fatal("expected session and user, got ", err)
+	}
+	if session.UserID != userId || session.IdleExpires != 2 || session.Attributes["country"] != "NZ" {
+		t.Fatalf("unexpected session %+v", session)
+	}
+	if joined.ID != userId || joined.SessionID != sessionId || joined.Attributes["age"] != int32(30) {
+		t.Fatalf("unexpected user %+v", joined)
+	}
+	if _, ok := joined.Attributes["country"]; ok {
+		t.Fatalf("session attributes leaked into the user: %v", joined.Attributes)
+	}
+}
@@ -0,0 +1,266 @@
+package postgresql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Projection lists the attributes read by GetSessionAndUser, on top of the
+// guam columns, which are always read.
+type Projection struct {
+	UserAttributes    []string
+	SessionAttributes []string
+}
+
+// WithSessionAndUserProjection limits GetSessionAndUser, which guam runs to
+// validate every session, to the attributes of projection. When attributes
+// are stored as JSON, the whole object is read if any attribute is listed.
+// The attribute names, like table names, are part of the program's
+// configuration, so it panics if one can't be escaped.
+func WithSessionAndUserProjection(projection Projection) Option {
+	projection.UserAttributes = mustValidateIdentifiers(projection.UserAttributes)
+	projection.SessionAttributes = mustValidateIdentifiers(projection.SessionAttributes)
+	return func(p *postgresAdapterImpl) {
+		p.projection = &projection
+	}
+}
+
+// selects holds the queries reading users and sessions. They list their
+// columns, which include the attribute columns of the tables; unless those
+// are declared with WithUserColumns and WithSessionColumns, they are read
+// from the database, so the queries are built on first use.
+type selects struct {
+	getUser             string
+	getSession          string
+	getSessionsByUserId string
+	getSessionAndUser   string
+	// userColumnCount is the number of user columns read by
+	// getSessionAndUser, which come before the session columns; both tables
+	// have an id column, and attributes can have any name, so the row is
+	// split by position.
+	userColumnCount int
+}
+
+// selectCache holds the selects once built. It is shared by the copies made
+// by WithContext.
+type selectCache struct {
+	mu      sync.Mutex
+	selects *selects
+	// refreshes counts the calls to refreshColumns, so that selects built
+	// from columns read before one aren't kept.
+	refreshes int
+}
+
+// loadSelects returns the queries reading users and sessions, building them
+// on first use. They aren't kept if a table has no columns yet.
+//
+// The lock isn't held while the columns are read: with a pool, that can take
+// a new connection, whose AfterConnect may be Prepare, which loads the
+// selects too.
+func (p *postgresAdapterImpl) loadSelects(ctx context.Context) (*selects, error) {
+	p.selects.mu.Lock()
+	cached, refreshes := p.selects.selects, p.selects.refreshes
+	p.selects.mu.Unlock()
+
+	if cached != nil {
+		return cached, nil
+	}
+
+	userColumns, complete, err := p.selectColumns(ctx, p.userAttributes)
+	if err != nil {
+		return nil, err
+	}
+	userId := dialect.Column(p.tables.UserColumns, "id")
+	s := &selects{
+		getUser: fmt.Sprintf(
+			"SELECT %s FROM %s WHERE %s = $1",
+			strings.Join(userColumns, ", "),
+			p.escapedUserTable,
+			userId,
+		),
+	}
+	if p.tables.Session == "" {
+		if complete {
+			p.cacheSelects(s, refreshes)
+		}
+		return s, nil
+	}
+
+	sessionColumns, sessionComplete, err := p.selectColumns(ctx, p.sessionAttributes)
+	if err != nil {
+		return nil, err
+	}
+	complete = complete && sessionComplete
+	sessionId := dialect.Column(p.tables.SessionColumns, "id")
+	sessionUserId := dialect.Column(p.tables.SessionColumns, "user_id")
+	s.getSession = fmt.Sprintf(
+		"SELECT %s FROM %s WHERE %s = $1",
+		strings.Join(sessionColumns, ", "),
+		p.escapedSessionTable,
+		sessionId,
+	)
+	s.getSessionsByUserId = fmt.Sprintf(
+		"SELECT %s FROM %s WHERE %s = $1",
+		strings.Join(sessionColumns, ", "),
+		p.escapedSessionTable,
+		sessionUserId,
+	)
+
+	if p.projection != nil {
+		userColumns = p.projectColumns(p.userAttributes, p.projection.UserAttributes)
+		sessionColumns = p.projectColumns(p.sessionAttributes, p.projection.SessionAttributes)
+	}
+	s.getSessionAndUser = fmt.Sprintf(
+		"SELECT %[3]s, %[4]s "+
+			"FROM %[2]s INNER JOIN %[1]s ON %[1]s.%[5]s = %[2]s.%[6]s WHERE %[2]s.%[7]s = $1",
+		p.escapedUserTable,
+		p.escapedSessionTable,
+		qualifyColumns(p.escapedUserTable, userColumns),
+		qualifyColumns(p.escapedSessionTable, sessionColumns),
+		userId,
+		sessionUserId,
+		sessionId,
+	)
+	s.userColumnCount = len(userColumns)
+
+	if complete {
+		p.cacheSelects(s, refreshes)
+	}
+	return s, nil
+}
+
+// cacheSelects keeps s unless the columns were refreshed since refreshes was
+// read.
+func (p *postgresAdapterImpl) cacheSelects(s *selects, refreshes int) {
+	p.selects.mu.Lock()
+	defer p.selects.mu.Unlock()
+
+	if p.selects.refreshes == refreshes {
+		p.selects.selects = s
+	}
+}
+
+// RefreshColumns forgets the columns read from the database and the selects
+// built from them. Columns are also read again when a select fails on a
+// dropped column, and when attributes are written to a column added since.
+func (p *postgresAdapterImpl) RefreshColumns() {
+	p.refreshColumns()
+}
+
+// refreshColumns forgets the columns read from the database and the selects
+// built from them, and reports whether there were any.
+func (p *postgresAdapterImpl) refreshColumns() bool {
+	p.selects.mu.Lock()
+	defer p.selects.mu.Unlock()
+
+	p.selects.selects = nil
+	p.selects.refreshes++
+	user := p.userAttributes.columns.Reset()
+	session := p.sessionAttributes.columns.Reset()
+	return user || session
+}
+
+// checkColumns refreshes the columns once attributes were written to columns
+// of table that weren't there when they were read. Only
+// AllowUnknownAttributes writes to columns it doesn't know; the other
+// policies check the columns before writing.
+func (p *postgresAdapterImpl) checkColumns(table *attributeTable, attributes map[string]any) {
+	if p.escapedJSONAttributes != "" || p.attributePolicy != AllowUnknownAttributes || len(attributes) == 0 {
+		return
+	}
+	columns := make([]string, 0, len(attributes))
+	for key := range attributes {
+		columns = append(columns, table.mapping.Column(key))
+	}
+	if table.columns.Missing(columns) {
+		p.logger.Debugln("Reading columns again for: ", columns)
+		p.refreshColumns()
+	}
+}
+
+// querySelect runs the select picked by query. If it fails on a column
+// dropped since the columns were read, they are read again and the select
+// runs once more.
+func (p *postgresAdapterImpl) querySelect(query func(s *selects) string, args ...any) ([]string, [][]any, error) {
+	for retry := true; ; retry = false {
+		selects, err := p.loadSelects(p.ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		q := query(selects)
+		p.logger.Debugln("Query: ", q)
+
+		columns, rows, err := p.queryRows(q, args...)
+		var pgErr *pgconn.PgError
+		if err != nil && retry && errors.As(err, &pgErr) && pgErr.Code == undefinedColumn {
+			p.logger.Debugln("Reading columns again after: ", err)
+			if p.refreshColumns() {
+				continue
+			}
+		}
+		return columns, rows, err
+	}
+}
+
+// selectColumns returns the escaped columns read from table: the guam columns,
+// then the JSON attributes column or the attribute columns. complete is false
+// if the columns had to be read from the database and none were found.
+func (p *postgresAdapterImpl) selectColumns(
+	ctx context.Context,
+	table *attributeTable,
+) (columns []string, complete bool, err error) {
+	columns = table.escapedCoreColumns()
+	if p.escapedJSONAttributes != "" {
+		return append(columns, p.escapedJSONAttributes), true, nil
+	}
+
+	known, err := p.knownColumns(ctx, table)
+	if err != nil {
+		return nil, false, err
+	}
+	var attributes []string
+	for column := range known {
+		if !table.isCoreColumn(column) {
+			attributes = append(attributes, column)
+		}
+	}
+	slices.Sort(attributes)
+	for _, attribute := range attributes {
+		columns = append(columns, quoteIdentifier(attribute))
+	}
+	return columns, len(known) > 0, nil
+}
+
+// projectColumns returns the escaped columns read from table to get the guam
+// columns and attributes.
+func (p *postgresAdapterImpl) projectColumns(table *attributeTable, attributes []string) []string {
+	columns := table.escapedCoreColumns()
+	if p.escapedJSONAttributes != "" {
+		if len(attributes) > 0 {
+			columns = append(columns, p.escapedJSONAttributes)
+		}
+		return columns
+	}
+	for _, attribute := range attributes {
+		if !table.isCoreColumn(attribute) {
+			columns = append(columns, quoteIdentifier(attribute))
+		}
+	}
+	return columns
+}
+
+// qualifyColumns prefixes the selected columns with their table; aliases
+// stay as they are.
+func qualifyColumns(table string, columns []string) string {
+	qualified := make([]string, len(columns))
+	for i, column := range columns {
+		qualified[i] = table + "." + column
+	}
+	return strings.Join(qualified, ", ")
+}
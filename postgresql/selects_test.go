@@ -0,0 +1,148 @@
+package postgresql
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/seatedro/guam/auth"
+	"github.com/seatedro/guam/utils"
+)
+
+func TestSelectsWithDeclaredColumns(t *testing.T) {
+	t.Parallel()
+
+	// A nil DB proves the declared columns are used instead of the schema.
+	adapter := PostgresAdapter(context.Background(), nil, Tables{
+		User:    "auth_user",
+		Session: "user_session",
+		Key:     "user_key",
+	}, false,
+		WithUserColumns("id", "username", "email"),
+		WithSessionColumns("id", "user_id", "active_expires", "idle_expires", "country"),
+		WithSessionAndUserProjection(Projection{UserAttributes: []string{"username"}}),
+	).(*postgresAdapterImpl)
+
+	selects, err := adapter.loadSelects(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `SELECT "id", "email", "username" FROM "auth_user" WHERE "id" = $1`
+	if selects.getUser != expected {
+		t.Fatalf("expected %s, got %s", expected, selects.getUser)
+	}
+	if !strings.Contains(selects.getSession, `"country"`) || strings.Contains(selects.getSession, "*") {
+		t.Fatalf("expected the session columns to be listed, got %s", selects.getSession)
+	}
+
+	query := selects.getSessionAndUser
+	if !strings.Contains(query, `"auth_user"."username"`) ||
+		strings.Contains(query, `"email"`) ||
+		strings.Contains(query, `"country"`) ||
+		strings.Contains(query, "*") {
+		t.Fatalf("expected the projection to be selected, got %s", query)
+	}
+}
+
+func TestRefreshColumns(t *testing.T) {
+	t.Parallel()
+
+	ctx, conn, adapter := setup(t)
+	userId := createUser(t, adapter, false)
+	if user, err := adapter.GetUser(userId); err != nil || user.Attributes["username"] == nil {
+		t.Fatalf("expected username, got %+v: %v", user, err)
+	}
+
+	if _, err := conn.Exec(ctx, "ALTER TABLE auth_user ADD COLUMN bio TEXT DEFAULT 'bio'"); err != nil {
+		t.Fatal(err)
+	}
+	adapter.RefreshColumns()
+	user, err := adapter.GetUser(userId)
+	if err != nil || user == nil || user.Attributes["bio"] != "bio" {
+		t.Fatalf("expected bio to be read, got %+v: %v", user, err)
+	}
+}
+
+func TestSelectsReadWrittenColumns(t *testing.T) {
+	t.Parallel()
+
+	ctx, conn, adapter := setup(t)
+	userId := createUser(t, adapter, false)
+	if _, err := adapter.GetUser(userId); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := conn.Exec(ctx, "ALTER TABLE auth_user ADD COLUMN bio TEXT"); err != nil {
+		t.Fatal(err)
+	}
+	if err := adapter.UpdateUser(userId, map[string]any{"bio": "bio"}); err != nil {
+		t.Fatal(err)
+	}
+	user, err := adapter.GetUser(userId)
+	if err != nil || user == nil || user.Attributes["bio"] != "bio" {
+		t.Fatalf("expected bio to be read, got %+v: %v", user, err)
+	}
+}
+
+func TestSelectsSurviveDroppedColumns(t *testing.T) {
+	t.Parallel()
+
+	ctx, conn, adapter := setup(t)
+	if _, err := conn.Exec(ctx, "ALTER TABLE auth_user ADD COLUMN bio TEXT"); err != nil {
+		t.Fatal(err)
+	}
+	userId := createUser(t, adapter, false)
+	if user, err := adapter.GetUser(userId); err != nil || user == nil {
+		t.Fatal("expected user, got ", err)
+	}
+
+	if _, err := conn.Exec(ctx, "ALTER TABLE auth_user DROP COLUMN bio"); err != nil {
+		t.Fatal(err)
+	}
+	user, err := adapter.GetUser(userId)
+	if err != nil || user == nil {
+		t.Fatal("expected user, got ", err)
+	}
+	if _, ok := user.Attributes["bio"]; ok {
+		t.Fatalf("expected bio not to be read, got %v", user.Attributes)
+	}
+}
+
+func TestSessionAndUserProjection(t *testing.T) {
+	t.Parallel()
+
+	ctx, conn, _ := setup(t)
+	if _, err := conn.Exec(ctx, "ALTER TABLE user_session ADD COLUMN country TEXT"); err != nil {
+		t.Fatal(err)
+	}
+	adapter := PostgresAdapter(ctx, conn, Tables{
+		User:    "auth_user",
+		Session: "user_session",
+		Key:     "user_key",
+	}, false, WithSessionAndUserProjection(Projection{}))
+
+	userId := createUser(t, adapter, false)
+	sessionId := utils.GenerateRandomString(5, "")
+	err := adapter.SetSession(auth.SessionSchema{
+		ID:            sessionId,
+		UserID:        userId,
+		ActiveExpires: 1,
+		IdleExpires:   2,
+		Attributes:    map[string]any{"country": "NZ"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	session, user, err := adapter.GetSessionAndUser(sessionId)
+	if err != nil || session == nil || user == nil {
+		t.Fatal("expected session and user, got ", err)
+	}
+	if session.IdleExpires != 2 || user.ID != userId || len(session.Attributes) != 0 || len(user.Attributes) != 0 {
+		t.Fatalf("expected only the guam columns, got %+v and %+v", session, user)
+	}
+
+	if session, err := adapter.GetSession(sessionId); err != nil || session.Attributes["country"] != "NZ" {
+		t.Fatalf("expected GetSession to read every attribute, got %+v: %v", session, err)
+	}
+}
@@ -0,0 +1,178 @@
+package postgresql
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/seatedro/guam/auth"
+)
+
+// statements holds the SQL of the queries whose text doesn't depend on their
+// arguments or on the database, built once by PostgresAdapter; selects holds
+// those reading users and sessions. Inserts cover the columns of a
+// zero schema, plus the JSON attributes column if there is one; the columns
+// are kept to tell whether an insert can use them.
+type statements struct {
+	insertUser       string
+	insertUserFields []string
+	deleteUser       string
+
+	insertSession          string
+	insertSessionFields    []string
+	deleteSession          string
+	deleteSessionsByUserId string
+	deleteExpiredSessions  string
+
+	getKey             string
+	getKeysByUserId    string
+	insertKey          string
+	insertKeyFields    []string
+	deleteKey          string
+	deleteKeysByUserId string
+}
+
+func insertQuery(table string, fields []string, placeholders []string) string {
+	return fmt.Sprintf(
+		"INSERT INTO %s ( %s ) VALUES ( %s )",
+		table,
+		strings.Join(fields, ", "),
+		strings.Join(placeholders, ", "),
+	)
+}
+
+// withJSONAttributes appends the JSON attributes column, if any, to the
+// columns of an insert.
+func (p *postgresAdapterImpl) withJSONAttributes(
+	fields []string,
+	placeholders []string,
+) ([]string, []string) {
+	if p.escapedJSONAttributes == "" {
+		return fields, placeholders
+	}
+	return append(fields, p.escapedJSONAttributes),
+		append(placeholders, fmt.Sprintf("$%d", len(placeholders)+1))
+}
+
+func (p *postgresAdapterImpl) newStatements() statements {
+	userFields, userPlaceholders, _ := p.userHelper(auth.UserSchema{})
+	userFields, userPlaceholders = p.withJSONAttributes(userFields, userPlaceholders)
+	keyFields, keyPlaceholders, _ := p.keyHelper(auth.KeySchema{})
+	var keyColumns []string
+	for _, column := range expectedColumns[auth.KeySchema]() {
+		keyColumns = append(keyColumns, dialect.SelectColumn(p.tables.KeyColumns, column.name))
+	}
+	keyId := dialect.Column(p.tables.KeyColumns, "id")
+	keyUserId := dialect.Column(p.tables.KeyColumns, "user_id")
+
+	s := statements{
+		insertUser:       insertQuery(p.escapedUserTable, userFields, userPlaceholders),
+		insertUserFields: userFields,
+		deleteUser: fmt.Sprintf(
+			"DELETE FROM %s WHERE %s = $1",
+			p.escapedUserTable,
+			dialect.Column(p.tables.UserColumns, "id"),
+		),
+
+		getKey: fmt.Sprintf(
+			"SELECT %s FROM %s WHERE %s = $1",
+			strings.Join(keyColumns, ", "),
+			p.escapedKeyTable,
+			keyId,
+		),
+		getKeysByUserId: fmt.Sprintf(
+			"SELECT %s FROM %s WHERE %s = $1",
+			strings.Join(keyColumns, ", "),
+			p.escapedKeyTable,
+			keyUserId,
+		),
+		insertKey:          insertQuery(p.escapedKeyTable, keyFields, keyPlaceholders),
+		insertKeyFields:    keyFields,
+		deleteKey:          fmt.Sprintf("DELETE FROM %s WHERE %s = $1", p.escapedKeyTable, keyId),
+		deleteKeysByUserId: fmt.Sprintf("DELETE FROM %s WHERE %s = $1", p.escapedKeyTable, keyUserId),
+	}
+	if p.tables.Session == "" {
+		return s
+	}
+
+	sessionFields, sessionPlaceholders, _ := p.sessionHelper(auth.SessionSchema{})
+	sessionFields, sessionPlaceholders = p.withJSONAttributes(sessionFields, sessionPlaceholders)
+	s.insertSession = insertQuery(p.escapedSessionTable, sessionFields, sessionPlaceholders)
+	s.insertSessionFields = sessionFields
+	sessionId := dialect.Column(p.tables.SessionColumns, "id")
+	s.deleteSession = fmt.Sprintf("DELETE FROM %s WHERE %s = $1", p.escapedSessionTable, sessionId)
+	s.deleteSessionsByUserId = fmt.Sprintf(
+		"DELETE FROM %s WHERE %s = $1",
+		p.escapedSessionTable,
+		dialect.Column(p.tables.SessionColumns, "user_id"),
+	)
+	s.deleteExpiredSessions = fmt.Sprintf(
+		"DELETE FROM %[1]s WHERE %[2]s IN ( SELECT %[2]s FROM %[1]s WHERE %[3]s < $1 LIMIT $2 )",
+		p.escapedSessionTable,
+		sessionId,
+		dialect.Column(p.tables.SessionColumns, "idle_expires"),
+	)
+	return s
+}
+
+// all returns every statement, skipping those of a missing session table.
+func (s statements) all() []string {
+	all := []string{
+		s.insertUser, s.deleteUser,
+		s.insertSession, s.deleteSession, s.deleteSessionsByUserId, s.deleteExpiredSessions,
+		s.getKey, s.getKeysByUserId, s.insertKey, s.deleteKey, s.deleteKeysByUserId,
+	}
+	statements := all[:0]
+	for _, statement := range all {
+		if statement != "" {
+			statements = append(statements, statement)
+		}
+	}
+	return statements
+}
+
+// insertStatement returns fixed if it inserts fields, which attributes and
+// omitempty fields can change, and builds the insert otherwise.
+func insertStatement(
+	fixed string,
+	fixedFields []string,
+	table string,
+	fields []string,
+	placeholders []string,
+) string {
+	if slices.Equal(fields, fixedFields) {
+		return fixed
+	}
+	return insertQuery(table, fields, placeholders)
+}
+
+func (p *postgresAdapterImpl) Prepare(ctx context.Context, conn *pgx.Conn) error {
+	// Read the columns through conn: a pool calling Prepare from AfterConnect
+	// may have no other connection to hand out until it returns.
+	adapter := *p
+	adapter.db = conn
+	selects, err := adapter.loadSelects(ctx)
+	if err != nil {
+		p.logger.Errorln("Error: ", err)
+		return err
+	}
+	statements := append(
+		p.statements.all(),
+		selects.getUser,
+		selects.getSession,
+		selects.getSessionsByUserId,
+		selects.getSessionAndUser,
+	)
+	for _, statement := range statements {
+		if statement == "" {
+			continue
+		}
+		if _, err := conn.Prepare(ctx, statement, statement); err != nil {
+			p.logger.Errorln("Error while preparing statement: ", err)
+			return err
+		}
+	}
+	return nil
+}
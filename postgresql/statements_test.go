@@ -0,0 +1,255 @@
+package postgresql
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/seatedro/guam/auth"
+	"github.com/seatedro/guam/utils"
+)
+
+func TestInsertStatements(t *testing.T) {
+	t.Parallel()
+
+	tables := Tables{User: "auth_user", Session: "user_session", Key: "user_key"}
+
+	adapter := PostgresAdapter(context.Background(), nil, tables, false).(*postgresAdapterImpl)
+	expected := `INSERT INTO "auth_user" ( "id" ) VALUES ( $1 )`
+	if adapter.statements.insertUser != expected {
+		t.Fatalf("expected %s, got %s", expected, adapter.statements.insertUser)
+	}
+
+	adapter = PostgresAdapter(context.Background(), nil, tables, false,
+		WithJSONAttributes("attributes"),
+	).(*postgresAdapterImpl)
+	expected = `INSERT INTO "auth_user" ( "id", "attributes" ) VALUES ( $1, $2 )`
+	if adapter.statements.insertUser != expected {
+		t.Fatalf("expected %s, got %s", expected, adapter.statements.insertUser)
+	}
+
+	adapter = PostgresAdapter(context.Background(), nil, Tables{User: "auth_user", Key: "user_key"}, false).(*postgresAdapterImpl)
+	if adapter.statements.insertSession != "" || len(adapter.statements.all()) != 7 {
+		t.Fatalf("expected no session statements, got %q", adapter.statements.all())
+	}
+}
+
+func TestMappedStatements(t *testing.T) {
+	t.Parallel()
+
+	adapter := PostgresAdapter(context.Background(), nil, Tables{
+		User:           "auth_user",
+		Session:        "user_session",
+		Key:            "user_key",
+		UserColumns:    Columns{"id": "uid"},
+		SessionColumns: Columns{"user_id": "account_id", "idle_expires": "expires_at_ms"},
+		KeyColumns:     Columns{"user_id": "account_id"},
+	}, false,
+		WithUserColumns("uid", "username"),
+		WithSessionColumns("id", "account_id", "active_expires", "expires_at_ms"),
+	).(*postgresAdapterImpl)
+
+	expected := `INSERT INTO "auth_user" ( "uid" ) VALUES ( $1 )`
+	if adapter.statements.insertUser != expected {
+		t.Fatalf("expected %s, got %s", expected, adapter.statements.insertUser)
+	}
+	expected = `DELETE FROM "user_key" WHERE "account_id" = $1`
+	if adapter.statements.deleteKeysByUserId != expected {
+		t.Fatalf("expected %s, got %s", expected, adapter.statements.deleteKeysByUserId)
+	}
+	if !strings.Contains(adapter.statements.deleteExpiredSessions, `"expires_at_ms" < $1`) {
+		t.Fatalf("expected the mapped expiry column, got %s", adapter.statements.deleteExpiredSessions)
+	}
+
+	selects, err := adapter.loadSelects(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = `SELECT "uid" AS "id", "username" FROM "auth_user" WHERE "uid" = $1`
+	if selects.getUser != expected {
+		t.Fatalf("expected %s, got %s", expected, selects.getUser)
+	}
+	if !strings.Contains(selects.getSessionAndUser, `ON "auth_user"."uid" = "user_session"."account_id"`) ||
+		!strings.Contains(selects.getSessionAndUser, `"user_session"."expires_at_ms" AS "idle_expires"`) ||
+		strings.Contains(selects.getSessionAndUser, `"user_session"."account_id",`) {
+		t.Fatalf("expected the mapped columns to be joined and aliased, got %s", selects.getSessionAndUser)
+	}
+
+	fields, _, _, err := adapter.updateAttributes(adapter.sessionAttributes, map[string]any{"idle_expires": 1})
+	if err != nil || len(fields) != 1 || fields[0] != `"expires_at_ms"` {
+		t.Fatalf("expected the mapped column to be updated, got %v: %v", fields, err)
+	}
+}
+
+func TestPrepare(t *testing.T) {
+	t.Parallel()
+
+	ctx, conn, adapter := setup(t)
+	if err := adapter.Prepare(ctx, conn); err != nil {
+		t.Fatal(err)
+	}
+
+	impl := adapter.(*postgresAdapterImpl)
+	selects, err := impl.loadSelects(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := len(impl.statements.all())
+	for _, query := range []string{selects.getUser, selects.getSession, selects.getSessionsByUserId, selects.getSessionAndUser} {
+		if query != "" {
+			expected++
+		}
+	}
+
+	var prepared int
+	err = conn.QueryRow(ctx, "SELECT count(*) FROM pg_prepared_statements").Scan(&prepared)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prepared != expected {
+		t.Fatalf("expected %d prepared statements, got %d", expected, prepared)
+	}
+
+	userId := createUser(t, adapter, false)
+	sessionId := utils.GenerateRandomString(5, "")
+	err = adapter.SetSession(auth.SessionSchema{ID: sessionId, UserID: userId, ActiveExpires: 1, IdleExpires: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if session, err := adapter.GetSession(sessionId); err != nil || session == nil {
+		t.Fatal("expected session, got ", err)
+	}
+}
+
+func TestPrepareAfterConnect(t *testing.T) {
+	t.Parallel()
+
+	ctx, conn, _ := setup(t)
+
+	config, err := pgxpool.ParseConfig(databaseURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.ConnConfig = conn.Config().Copy()
+	config.MaxConns = 4
+
+	// The adapter reads its columns through the pool that prepares its
+	// queries on every new connection.
+	var adapter Adapter
+	config.AfterConnect = func(ctx context.Context, conn *pgx.Conn) error {
+		return adapter.Prepare(ctx, conn)
+	}
+	pool, err := pgxpool.NewWithConfig(ctx, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(pool.Close)
+	adapter = PostgresAdapter(ctx, pool, Tables{
+		User:    "auth_user",
+		Session: "user_session",
+		Key:     "user_key",
+	}, false)
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	timed := adapter.WithContext(ctx)
+
+	userId := createUser(t, timed, true)
+	var wg sync.WaitGroup
+	errs := make([]error, 8)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = timed.GetUser(userId)
+		}(i)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkSessionHelper(b *testing.B) {
+	adapter := PostgresAdapter(context.Background(), nil, Tables{Session: "user_session"}, false).(*postgresAdapterImpl)
+	session := auth.SessionSchema{ID: "session", UserID: "user", ActiveExpires: 1, IdleExpires: 2}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		adapter.sessionHelper(session)
+	}
+}
+
+// benchmarkAdapter returns an adapter with a user to attach sessions to, with
+// its statements prepared if prepare is set.
+func benchmarkAdapter(b *testing.B, prepare bool) (Adapter, string) {
+	ctx, conn, adapter := setup(b)
+	if prepare {
+		if err := adapter.Prepare(ctx, conn); err != nil {
+			b.Fatal(err)
+		}
+	}
+	return adapter, createUser(b, adapter, false)
+}
+
+func BenchmarkSetSession(b *testing.B) {
+	for _, prepare := range []bool{false, true} {
+		name := "Unprepared"
+		if prepare {
+			name = "Prepared"
+		}
+		b.Run(name, func(b *testing.B) {
+			adapter, userId := benchmarkAdapter(b, prepare)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				err := adapter.SetSession(auth.SessionSchema{
+					ID:            utils.GenerateRandomString(16, ""),
+					UserID:        userId,
+					ActiveExpires: 1,
+					IdleExpires:   2,
+				})
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkGetSession(b *testing.B) {
+	for _, prepare := range []bool{false, true} {
+		name := "Unprepared"
+		if prepare {
+			name = "Prepared"
+		}
+		b.Run(name, func(b *testing.B) {
+			adapter, userId := benchmarkAdapter(b, prepare)
+			sessionId := utils.GenerateRandomString(16, "")
+			err := adapter.SetSession(auth.SessionSchema{
+				ID:            sessionId,
+				UserID:        userId,
+				ActiveExpires: 1,
+				IdleExpires:   2,
+			})
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := adapter.GetSession(sessionId); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -1,18 +1,76 @@
 package postgresql
 
 import (
-	"reflect"
-	"strings"
+	"slices"
+
+	"github.com/seatedro/guam-adapters/internal/sqlutil"
 )
 
 const EscapeChar = `"`
 
-// EscapeName escapes a database name (table or column) unless it's schema-qualified.
+// maxIdentifierLength is the longest identifier the database accepts.
+const maxIdentifierLength = 63
+
+var dialect = sqlutil.Dialect{EscapeChar: EscapeChar, MaxIdentifierLength: maxIdentifierLength}
+
+// ErrInvalidName is returned when a table or column name can't be escaped.
+var ErrInvalidName = sqlutil.ErrInvalidName
+
+// EscapeName escapes a table name, which may be schema-qualified. Each part of
+// "schema.table" is quoted separately and embedded quote characters are
+// doubled, so the result always names exactly one table. It panics if the
+// name can't be escaped; check names coming from elsewhere with ValidateName
+// first.
 func EscapeName(val string) string {
-	if strings.Contains(val, ".") {
-		return val
+	escaped, err := dialect.EscapeName(val)
+	if err != nil {
+		panic(err)
 	}
-	return EscapeChar + val + EscapeChar
+	return escaped
+}
+
+// ValidateName returns an error wrapping ErrInvalidName if EscapeName can't
+// escape val: names with an empty part, a NUL character, invalid UTF-8 or a
+// part longer than the database allows.
+func ValidateName(val string) error {
+	_, err := dialect.EscapeName(val)
+	return err
+}
+
+// EscapeIdentifier escapes a single identifier, such as a column name. Unlike
+// EscapeName, dots are part of the name.
+func EscapeIdentifier(val string) (string, error) {
+	return dialect.EscapeIdentifier(val)
+}
+
+func quoteIdentifier(val string) string {
+	return dialect.QuoteIdentifier(val)
+}
+
+// mustEscapeName escapes a table name from the adapter's configuration, where
+// an invalid name is a programming error. An empty name, used for an optional
+// table, stays empty.
+func mustEscapeName(val string) string {
+	return dialect.MustEscapeName(val)
+}
+
+// mustEscapeIdentifier escapes a column name given to an option, which, like
+// a table name, is part of the program's configuration.
+func mustEscapeIdentifier(val string) string {
+	escaped, err := EscapeIdentifier(val)
+	if err != nil {
+		panic(err)
+	}
+	return escaped
+}
+
+// mustValidateIdentifiers returns a copy of names, which the caller may
+// change once the option is built, after checking that each can be escaped.
+func mustValidateIdentifiers(names []string) []string {
+	for _, name := range names {
+		mustEscapeIdentifier(name)
+	}
+	return slices.Clone(names)
 }
 
 type (
@@ -20,41 +78,27 @@ type (
 	HelperFunc[T any] func(values T) ([]string, []string, []interface{})
 )
 
+// CreatePreparedStatementHelper returns a function listing the columns, the
+// placeholders and the values of an insert of a T. The fields are looked up
+// once, here, rather than on every call.
+//
+// Fields are mapped by their db tag, which can be followed by options:
+// omitempty leaves zero values out so that column defaults apply, and
+// readonly leaves the field out altogether. The fields of untagged embedded
+// structs are included as if they were fields of T; those of a nil embedded
+// pointer are NULL.
 func CreatePreparedStatementHelper[T any](placeholder PlaceHolderFunc) HelperFunc[T] {
-	return func(values T) ([]string, []string, []interface{}) {
-		v := reflect.ValueOf(values)
-		t := v.Type()
-
-		var fields []string
-		var placeholders []string
-		var args []interface{}
-
-		n := 0
-		for i := 0; i < v.NumField(); i++ {
-			field := t.Field(i)
-			if field.Name == "Attributes" {
-				continue
-			}
-			tag := field.Tag.Get("db")
-			if tag == "" || tag == "-" || len(tag) == 0 {
-				continue
-			}
-			fields = append(fields, EscapeName(tag))
-			placeholders = append(placeholders, placeholder(n))
-			args = append(args, v.Field(i).Interface())
-			n++
-		}
-
-		return fields, placeholders, args
-	}
+	return CreateMappedStatementHelper[T](placeholder, nil)
 }
 
-func GetSetArgs(fields []string, placeholders []string) string {
-	var setArgs []string
-	for i, field := range fields {
-		setArg := field + " = " + placeholders[i]
-		setArgs = append(setArgs, setArg)
-	}
+// CreateMappedStatementHelper is CreatePreparedStatementHelper for a table
+// whose columns are renamed by mapping.
+func CreateMappedStatementHelper[T any](placeholder PlaceHolderFunc, mapping Columns) HelperFunc[T] {
+	return sqlutil.InsertHelper[T](placeholder, func(name string) string {
+		return dialect.Column(mapping, name)
+	})
+}
 
-	return strings.Join(setArgs, ", ")
+func GetSetArgs(fields []string, placeholders []string) string {
+	return sqlutil.SetArgs(fields, placeholders)
 }
@@ -0,0 +1,163 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/seatedro/guam/auth"
+)
+
+// FuzzUpdateQuery checks that an attribute name can only ever end up as one
+// column in the SET clause of an update, whatever it contains.
+func FuzzUpdateQuery(f *testing.F) {
+	f.Add("username")
+	f.Add(`username" = 'admin', "id`)
+	f.Add("username = $2 --")
+	f.Add("a.b")
+	f.Fuzz(func(t *testing.T, attribute string) {
+		fields, placeholders, args, err := appendAttributes(nil, nil, nil, map[string]any{
+			attribute: "value",
+		})
+		if err != nil {
+			if !errors.Is(err, ErrInvalidName) {
+				t.Fatalf("unexpected error for %q: %v", attribute, err)
+			}
+			return
+		}
+		if len(args) != 1 || len(placeholders) != 1 || placeholders[0] != "$1" {
+			t.Fatalf("unexpected placeholders %q and args %v", placeholders, args)
+		}
+
+		set := GetSetArgs(fields, placeholders)
+		column, ok := strings.CutSuffix(set, " = $1")
+		if !ok {
+			t.Fatalf("unexpected SET clause %s", set)
+		}
+		if column != quoteIdentifier(attribute) {
+			t.Fatalf("%q became %s in the SET clause", attribute, set)
+		}
+	})
+}
+
+// FuzzInsertQuery checks that attribute names can't add columns or values to
+// the insert of a user beyond their own.
+func FuzzInsertQuery(f *testing.F) {
+	f.Add("username", "email")
+	f.Add(`username", "id`, "email")
+	f.Add("username ) VALUES ( $1 ); --", "id")
+	f.Fuzz(func(t *testing.T, first string, second string) {
+		if first == second || first == "id" || second == "id" {
+			return
+		}
+		adapter := PostgresAdapter(context.Background(), nil, Tables{User: "auth_user"}, false).(*postgresAdapterImpl)
+
+		fields, placeholders, args := adapter.userHelper(auth.UserSchema{ID: "user"})
+		fields, placeholders, args, err := appendAttributes(fields, placeholders, args, map[string]any{
+			first:  1,
+			second: 2,
+		})
+		if err != nil {
+			if !errors.Is(err, ErrInvalidName) {
+				t.Fatalf("unexpected error for %q and %q: %v", first, second, err)
+			}
+			return
+		}
+
+		// Escaping itself is fuzzed in sqlutil; here the columns only need to
+		// be the escaped attributes.
+		columns := make(map[string]bool)
+		for _, field := range fields {
+			columns[field] = true
+		}
+		if len(fields) != 3 || !columns[quoteIdentifier("id")] ||
+			!columns[quoteIdentifier(first)] || !columns[quoteIdentifier(second)] {
+			t.Fatalf("expected columns id, %q and %q, got %v", first, second, fields)
+		}
+		for i, placeholder := range placeholders {
+			if placeholder != fmt.Sprintf("$%d", i+1) {
+				t.Fatalf("unexpected placeholders %q", placeholders)
+			}
+		}
+		if len(args) != len(fields) {
+			t.Fatalf("expected %d args, got %d", len(fields), len(args))
+		}
+	})
+}
+
+type profile struct {
+	Bio string `db:"bio"`
+}
+
+type extendedUser struct {
+	auth.UserSchema
+	*profile
+	Email     *string        `db:"email"`
+	Nickname  string         `db:"nickname,omitempty"`
+	Country   sql.NullString `db:"country"`
+	CreatedAt time.Time      `db:"created_at,readonly"`
+	internal  string
+}
+
+func TestCreatePreparedStatementHelper(t *testing.T) {
+	helper := CreatePreparedStatementHelper[extendedUser](func(index int) string {
+		return fmt.Sprintf("$%d", index+1)
+	})
+
+	fields, placeholders, args := helper(extendedUser{UserSchema: auth.UserSchema{ID: "user"}})
+	expected := []string{`"id"`, `"bio"`, `"email"`, `"country"`}
+	if !slices.Equal(fields, expected) || len(placeholders) != 4 || len(args) != 4 {
+		t.Fatalf("expected columns %q, got %q %q", expected, fields, placeholders)
+	}
+	if args[0] != "user" || args[1] != nil || args[2] != nil || args[3] != (sql.NullString{}) {
+		t.Fatalf("unexpected args %#v", args)
+	}
+
+	email := "guam@example.com"
+	fields, placeholders, args = helper(extendedUser{
+		UserSchema: auth.UserSchema{ID: "user"},
+		profile:    &profile{Bio: "bio"},
+		Email:      &email,
+		Nickname:   "guam",
+		Country:    sql.NullString{String: "NZ", Valid: true},
+	})
+	expected = []string{`"id"`, `"bio"`, `"email"`, `"nickname"`, `"country"`}
+	if !slices.Equal(fields, expected) || len(placeholders) != 5 {
+		t.Fatalf("expected columns %q, got %q %q", expected, fields, placeholders)
+	}
+	if args[1] != "bio" || args[2] != email || args[3] != "guam" || args[4] != (sql.NullString{String: "NZ", Valid: true}) {
+		t.Fatalf("unexpected args %#v", args)
+	}
+}
+
+func TestEscapeName(t *testing.T) {
+	if escaped := EscapeName(`auth.user"s`); escaped != `"auth"."user""s"` {
+		t.Fatalf(`expected "auth"."user""s", got %s`, escaped)
+	}
+	if err := ValidateName("auth..user"); !errors.Is(err, ErrInvalidName) {
+		t.Fatalf("expected %v, got %v", ErrInvalidName, err)
+	}
+
+	defer func() {
+		if err, _ := recover().(error); !errors.Is(err, ErrInvalidName) {
+			t.Fatalf("expected a panic with %v, got %v", ErrInvalidName, err)
+		}
+	}()
+	EscapeName("auth..user")
+	t.Fatal("expected EscapeName to panic")
+}
+
+func TestProjectionRejectsInvalidNames(t *testing.T) {
+	defer func() {
+		if err, _ := recover().(error); !errors.Is(err, ErrInvalidName) {
+			t.Fatalf("expected a panic with %v, got %v", ErrInvalidName, err)
+		}
+	}()
+	WithSessionAndUserProjection(Projection{SessionAttributes: []string{strings.Repeat("a", maxIdentifierLength+1)}})
+	t.Fatal("expected WithSessionAndUserProjection to panic")
+}
@@ -0,0 +1,202 @@
+package postgresql
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/seatedro/guam-adapters/internal/sqlutil"
+	"github.com/seatedro/guam/auth"
+)
+
+// compatibleTypes lists the information_schema data types each Go kind can be
+// scanned from.
+var compatibleTypes = map[reflect.Kind][]string{
+	reflect.String: {"text", "character varying", "character", "uuid"},
+	reflect.Int64:  {"bigint", "integer", "smallint"},
+	reflect.Int32:  {"integer", "smallint"},
+	reflect.Bool:   {"boolean"},
+}
+
+// SchemaMismatch describes a column of a configured table that doesn't match
+// the guam schema.
+type SchemaMismatch struct {
+	Table  string
+	Column string
+	// Expected lists the accepted data types. Actual is empty when the column
+	// is missing.
+	Expected []string
+	Actual   string
+}
+
+func (m SchemaMismatch) String() string {
+	if m.Column == "" {
+		return fmt.Sprintf("table %s does not exist", m.Table)
+	}
+	if m.Actual == "" {
+		return fmt.Sprintf("%s.%s is missing", m.Table, m.Column)
+	}
+	return fmt.Sprintf(
+		"%s.%s is %s, expected %s",
+		m.Table,
+		m.Column,
+		m.Actual,
+		strings.Join(m.Expected, " or "),
+	)
+}
+
+// SchemaError is returned by Validate and lists every mismatch it found.
+type SchemaError struct {
+	Mismatches []SchemaMismatch
+}
+
+func (e *SchemaError) Error() string {
+	mismatches := make([]string, len(e.Mismatches))
+	for i, m := range e.Mismatches {
+		mismatches[i] = m.String()
+	}
+	return "postgresql: invalid schema: " + strings.Join(mismatches, "; ")
+}
+
+type expectedColumn struct {
+	name  string
+	types []string
+}
+
+// expectedColumns returns the columns declared by the db tags of T.
+func expectedColumns[T any]() []expectedColumn {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+
+	var columns []expectedColumn
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Name == "Attributes" {
+			continue
+		}
+		tag, _, _ := sqlutil.ParseTag(field.Tag.Get("db"))
+		if tag == "" {
+			continue
+		}
+		kind := field.Type.Kind()
+		if kind == reflect.Pointer {
+			kind = field.Type.Elem().Kind()
+		}
+		columns = append(columns, expectedColumn{name: tag, types: compatibleTypes[kind]})
+	}
+	return columns
+}
+
+func (p *postgresAdapterImpl) Validate(ctx context.Context) error {
+	var mismatches []SchemaMismatch
+
+	check := func(table string, escapedTable string, expected []expectedColumn) error {
+		actual, err := p.columnTypes(ctx, escapedTable)
+		if err != nil {
+			return err
+		}
+		if len(actual) == 0 {
+			mismatches = append(mismatches, SchemaMismatch{Table: table})
+			return nil
+		}
+		for _, column := range expected {
+			dataType, ok := actual[column.name]
+			if ok && (column.types == nil || slices.Contains(column.types, dataType)) {
+				continue
+			}
+			mismatches = append(mismatches, SchemaMismatch{
+				Table:    table,
+				Column:   column.name,
+				Expected: column.types,
+				Actual:   dataType,
+			})
+		}
+		return nil
+	}
+
+	err := check(p.tables.User, p.escapedUserTable, p.attributeColumns(
+		p.userAttributes,
+		expectedColumns[auth.UserSchema](),
+	))
+	if err != nil {
+		return err
+	}
+	err = check(p.tables.Key, p.escapedKeyTable, renameColumns(
+		p.tables.KeyColumns,
+		expectedColumns[auth.KeySchema](),
+	))
+	if err != nil {
+		return err
+	}
+	if p.tables.Session != "" {
+		err = check(p.tables.Session, p.escapedSessionTable, p.attributeColumns(
+			p.sessionAttributes,
+			expectedColumns[auth.SessionSchema](),
+		))
+		if err != nil {
+			return err
+		}
+	}
+
+	if mismatches != nil {
+		err := &SchemaError{Mismatches: mismatches}
+		p.logger.Errorln("Error: ", err)
+		return err
+	}
+	return nil
+}
+
+// renameColumns returns expected with the columns renamed by mapping.
+func renameColumns(mapping Columns, expected []expectedColumn) []expectedColumn {
+	renamed := make([]expectedColumn, len(expected))
+	for i, column := range expected {
+		renamed[i] = expectedColumn{name: mapping.Column(column.name), types: column.types}
+	}
+	return renamed
+}
+
+// attributeColumns returns the columns expected in table: those of the guam
+// schema, then the JSON attributes column or the columns declared with
+// WithUserColumns and WithSessionColumns, which can be of any type.
+func (p *postgresAdapterImpl) attributeColumns(table *attributeTable, expected []expectedColumn) []expectedColumn {
+	columns := renameColumns(table.mapping, expected)
+	if p.jsonAttributes != "" {
+		return append(columns, expectedColumn{name: p.jsonAttributes, types: []string{"jsonb"}})
+	}
+	for _, declared := range table.columns.Declared() {
+		if !slices.ContainsFunc(columns, func(column expectedColumn) bool {
+			return column.name == declared
+		}) {
+			columns = append(columns, expectedColumn{name: declared})
+		}
+	}
+	return columns
+}
+
+// columnTypes returns the data type of every column of table, keyed by column
+// name. The table is resolved through the search path like any other query.
+func (p *postgresAdapterImpl) columnTypes(ctx context.Context, table string) (map[string]string, error) {
+	query := "SELECT c.column_name, c.data_type FROM information_schema.columns c " +
+		"JOIN pg_catalog.pg_class t ON t.relname = c.table_name " +
+		"JOIN pg_catalog.pg_namespace n ON n.oid = t.relnamespace AND n.nspname = c.table_schema " +
+		"WHERE t.oid = to_regclass($1)"
+
+	p.logger.Debugln("Query: ", query)
+	rows, err := p.db.Query(ctx, query, table)
+	if err != nil {
+		p.logger.Errorln("Error while fetching columns: ", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns := make(map[string]string)
+	for rows.Next() {
+		var name, dataType string
+		if err := rows.Scan(&name, &dataType); err != nil {
+			return nil, err
+		}
+		columns[name] = dataType
+	}
+	return columns, rows.Err()
+}
@@ -0,0 +1,142 @@
+package postgresql
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	t.Parallel()
+
+	ctx, conn, adapter := setup(t)
+	tables := Tables{
+		User:    "auth_user",
+		Session: "user_session",
+		Key:     "user_key",
+	}
+
+	if err := adapter.Validate(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := conn.Exec(ctx, fmt.Sprintf("ALTER TABLE %s DROP COLUMN idle_expires", tables.Session))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = conn.Exec(ctx, fmt.Sprintf("ALTER TABLE %s ALTER COLUMN hashed_password TYPE bytea USING NULL", tables.Key))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var schemaErr *SchemaError
+	if err := adapter.Validate(ctx); !errors.As(err, &schemaErr) {
+		t.Fatal("expected a SchemaError, got ", err)
+	}
+	expected := map[string]string{
+		"idle_expires":    "",
+		"hashed_password": "bytea",
+	}
+	if len(schemaErr.Mismatches) != len(expected) {
+		t.Fatalf("expected %d mismatches, got %+v", len(expected), schemaErr.Mismatches)
+	}
+	for _, m := range schemaErr.Mismatches {
+		if actual, ok := expected[m.Column]; !ok || actual != m.Actual {
+			t.Fatalf("unexpected mismatch %+v", m)
+		}
+	}
+}
+
+func TestValidateMissingTable(t *testing.T) {
+	t.Parallel()
+
+	ctx, conn, _ := setup(t)
+
+	adapter := PostgresAdapter(ctx, conn, Tables{
+		User:    "auth_user",
+		Session: "user_session",
+		Key:     "missing_user_key",
+	}, false)
+
+	var schemaErr *SchemaError
+	if err := adapter.Validate(ctx); !errors.As(err, &schemaErr) {
+		t.Fatal("expected a SchemaError, got ", err)
+	}
+	if len(schemaErr.Mismatches) != 1 || schemaErr.Mismatches[0].Table != "missing_user_key" {
+		t.Fatalf("unexpected mismatches %+v", schemaErr.Mismatches)
+	}
+}
+
+func TestValidateJSONAttributes(t *testing.T) {
+	t.Parallel()
+
+	ctx, conn, _ := setup(t)
+	tables := Tables{
+		User:    "auth_user",
+		Session: "user_session",
+		Key:     "user_key",
+	}
+	adapter := PostgresAdapter(ctx, conn, tables, false, WithJSONAttributes("attributes"))
+
+	// The column is missing from both tables.
+	var schemaErr *SchemaError
+	if err := adapter.Validate(ctx); !errors.As(err, &schemaErr) {
+		t.Fatal("expected a SchemaError, got ", err)
+	}
+	if len(schemaErr.Mismatches) != 2 {
+		t.Fatalf("expected 2 mismatches, got %+v", schemaErr.Mismatches)
+	}
+	for _, m := range schemaErr.Mismatches {
+		if m.Column != "attributes" || m.Actual != "" {
+			t.Fatalf("unexpected mismatch %+v", m)
+		}
+	}
+
+	_, err := conn.Exec(ctx, "ALTER TABLE auth_user ADD COLUMN attributes JSONB NOT NULL DEFAULT '{}'")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = conn.Exec(ctx, "ALTER TABLE user_session ADD COLUMN attributes TEXT")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := adapter.Validate(ctx); !errors.As(err, &schemaErr) {
+		t.Fatal("expected a SchemaError, got ", err)
+	}
+	if len(schemaErr.Mismatches) != 1 ||
+		schemaErr.Mismatches[0].Table != "user_session" ||
+		schemaErr.Mismatches[0].Actual != "text" {
+		t.Fatalf("unexpected mismatches %+v", schemaErr.Mismatches)
+	}
+}
+
+func TestValidateDeclaredColumns(t *testing.T) {
+	t.Parallel()
+
+	ctx, conn, _ := setup(t)
+	adapter := PostgresAdapter(ctx, conn, Tables{
+		User:    "auth_user",
+		Session: "user_session",
+		Key:     "user_key",
+	}, false,
+		WithUserColumns("id", "username", "bio"),
+		WithSessionColumns("id", "user_id", "active_expires", "idle_expires"),
+	)
+
+	var schemaErr *SchemaError
+	if err := adapter.Validate(ctx); !errors.As(err, &schemaErr) {
+		t.Fatal("expected a SchemaError, got ", err)
+	}
+	if len(schemaErr.Mismatches) != 1 ||
+		schemaErr.Mismatches[0].Table != "auth_user" ||
+		schemaErr.Mismatches[0].Column != "bio" {
+		t.Fatalf("unexpected mismatches %+v", schemaErr.Mismatches)
+	}
+
+	if _, err := conn.Exec(ctx, "ALTER TABLE auth_user ADD COLUMN bio TEXT"); err != nil {
+		t.Fatal(err)
+	}
+	if err := adapter.Validate(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,26 @@
+module github.com/seatedro/guam-adapters/redis
+
+go 1.21.0
+
+require (
+	github.com/alicebob/miniredis/v2 v2.31.1
+	github.com/redis/go-redis/v9 v9.3.0
+	github.com/seatedro/guam v0.0.3
+	github.com/seatedro/guam-adapters/adaptertest v0.0.0
+	github.com/seatedro/guam-adapters/internal v0.0.0
+	go.uber.org/zap v1.26.0
+)
+
+require (
+	github.com/alicebob/gopher-json v0.0.0-20200520072559-a9ecdc9d1d3a // indirect
+	github.com/cespare/xxhash/v2 v2.2.0 // indirect
+	github.com/dgryski/go-rendezvous v0.0.0-20200823014737-9f7001d12a5f // indirect
+	github.com/yuin/gopher-lua v1.1.0 // indirect
+	go.uber.org/multierr v1.10.0 // indirect
+)
+
+replace (
+	github.com/seatedro/guam => ../../guam
+	github.com/seatedro/guam-adapters/adaptertest => ../adaptertest
+	github.com/seatedro/guam-adapters/internal => ../internal
+)
@@ -0,0 +1,421 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/seatedro/guam-adapters/internal/sqlutil"
+	"github.com/seatedro/guam/auth"
+	"go.uber.org/zap"
+)
+
+// AUTH_DUPLICATE_SESSION_ID is the message of the guam error returned by
+// SetSession for a session id that is already taken.
+const AUTH_DUPLICATE_SESSION_ID auth.ErrorMessage = "AUTH_DUPLICATE_SESSION_ID"
+
+// Prefixes are prepended to the ids of sessions and users to build their keys.
+type Prefixes struct {
+	// Session prefixes the key holding a session, e.g. "session:<id>".
+	Session string
+	// UserSessions prefixes the set of session ids of a user, e.g.
+	// "user_sessions:<user id>".
+	UserSessions string
+}
+
+// SessionAdapter is the session adapter returned by RedisSessionAdapter. It
+// only stores sessions; users and keys live in another adapter.
+type SessionAdapter interface {
+	GetSession(sessionId string) (*auth.SessionSchema, error)
+	GetSessionsByUserId(userId string) ([]auth.SessionSchema, error)
+	SetSession(session auth.SessionSchema) error
+	DeleteSession(sessionId string) error
+	DeleteSessionsByUserId(userId string) error
+	UpdateSession(sessionId string, partialSession map[string]any) error
+
+	// WithContext returns a copy of the adapter whose commands run with ctx
+	// instead of the context passed to RedisSessionAdapter.
+	WithContext(ctx context.Context) SessionAdapter
+}
+
+type redisSessionAdapterImpl struct {
+	ctx      context.Context
+	client   *redis.Client
+	logger   sqlutil.Logger
+	prefixes Prefixes
+}
+
+// Option configures the adapter returned by RedisSessionAdapter.
+type Option func(r *redisSessionAdapterImpl)
+
+// WithLogger logs through l instead of a logger built from debugMode. A nil l
+// keeps the default logger.
+func WithLogger(l *zap.Logger) Option {
+	return func(r *redisSessionAdapterImpl) {
+		if l != nil {
+			r.logger = l.Sugar()
+		}
+	}
+}
+
+// WithSlogLogger logs through l instead of a logger built from debugMode.
+// Sessions are logged at debug level, failures at error level. A nil l keeps
+// the default logger.
+func WithSlogLogger(l *slog.Logger) Option {
+	return func(r *redisSessionAdapterImpl) {
+		if l != nil {
+			r.logger = sqlutil.SlogLogger{L: l}
+		}
+	}
+}
+
+// storedSession is the JSON document stored for every session.
+type storedSession struct {
+	ID            string         `json:"id"`
+	UserID        string         `json:"user_id"`
+	ActiveExpires int64          `json:"active_expires"`
+	IdleExpires   int64          `json:"idle_expires"`
+	Attributes    map[string]any `json:"attributes,omitempty"`
+}
+
+// RedisSessionAdapter returns an adapter storing sessions in Redis. Each
+// session expires at its idle_expires, and the ids of a user's sessions are
+// kept in a set so they can be listed and deleted together. The set expires
+// with the last of its sessions, which requires Redis 7.0 or later.
+//
+// A session and the set of its user's sessions are written in one
+// transaction, and a user's sessions are read with one MGET. Their keys
+// don't share a hash slot, so client talks to a single server, or to the
+// primary of a failover setup, rather than to a cluster.
+func RedisSessionAdapter(
+	ctx context.Context,
+	client *redis.Client,
+	prefixes Prefixes,
+	debugMode bool,
+	opts ...Option,
+) SessionAdapter {
+	if prefixes.Session == "" {
+		prefixes.Session = "session"
+	}
+	if prefixes.UserSessions == "" {
+		prefixes.UserSessions = "user_sessions"
+	}
+	r := &redisSessionAdapterImpl{
+		ctx:      ctx,
+		client:   client,
+		prefixes: prefixes,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	if r.logger == nil {
+		r.logger = sqlutil.NewLogger(debugMode)
+	}
+	return r
+}
+
+func (r *redisSessionAdapterImpl) WithContext(ctx context.Context) SessionAdapter {
+	adapter := *r
+	adapter.ctx = ctx
+	return &adapter
+}
+
+func (r *redisSessionAdapterImpl) sessionKey(sessionId string) string {
+	return r.prefixes.Session + ":" + sessionId
+}
+
+func (r *redisSessionAdapterImpl) userSessionsKey(userId string) string {
+	return r.prefixes.UserSessions + ":" + userId
+}
+
+func toSchema(s storedSession) auth.SessionSchema {
+	return auth.SessionSchema{
+		ID:            s.ID,
+		UserID:        s.UserID,
+		ActiveExpires: s.ActiveExpires,
+		IdleExpires:   s.IdleExpires,
+		Attributes:    s.Attributes,
+	}
+}
+
+func fromSchema(s auth.SessionSchema) storedSession {
+	return storedSession{
+		ID:            s.ID,
+		UserID:        s.UserID,
+		ActiveExpires: s.ActiveExpires,
+		IdleExpires:   s.IdleExpires,
+		Attributes:    s.Attributes,
+	}
+}
+
+// getStoredSession returns nil if the session doesn't exist or has expired.
+func (r *redisSessionAdapterImpl) getStoredSession(
+	ctx context.Context,
+	getter redis.Cmdable,
+	sessionId string,
+) (*storedSession, error) {
+	data, err := getter.Get(ctx, r.sessionKey(sessionId)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var session storedSession
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// setStoredSession queues the commands storing session on pipe.
+func (r *redisSessionAdapterImpl) setStoredSession(
+	ctx context.Context,
+	pipe redis.Pipeliner,
+	session storedSession,
+) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+	key := r.sessionKey(session.ID)
+	pipe.Set(ctx, key, data, 0)
+	pipe.PExpireAt(ctx, key, time.UnixMilli(session.IdleExpires))
+
+	// The set of the user's sessions lives as long as the latest of them: NX
+	// sets the expiry of a new set and GT only ever extends it. Both options
+	// were added in Redis 7.0; older servers fail the whole transaction.
+	userSessionsKey := r.userSessionsKey(session.UserID)
+	pipe.SAdd(ctx, userSessionsKey, session.ID)
+	pipe.Do(ctx, "PEXPIREAT", userSessionsKey, session.IdleExpires, "NX")
+	pipe.Do(ctx, "PEXPIREAT", userSessionsKey, session.IdleExpires, "GT")
+	return nil
+}
+
+func (r *redisSessionAdapterImpl) GetSession(
+	sessionId string,
+) (*auth.SessionSchema, error) {
+	session, err := r.getStoredSession(r.ctx, r.client, sessionId)
+	if err != nil {
+		r.logger.Errorln("Error while fetching Session: ", err)
+		return nil, err
+	}
+	r.logger.Debugf("Session: %+v\n", session)
+	if session == nil {
+		return nil, nil
+	}
+	schema := toSchema(*session)
+	return &schema, nil
+}
+
+func (r *redisSessionAdapterImpl) GetSessionsByUserId(
+	userId string,
+) ([]auth.SessionSchema, error) {
+	userSessionsKey := r.userSessionsKey(userId)
+	sessionIds, err := r.client.SMembers(r.ctx, userSessionsKey).Result()
+	if err != nil {
+		r.logger.Errorln("Error while fetching Sessions: ", err)
+		return nil, err
+	}
+	if len(sessionIds) == 0 {
+		return nil, nil
+	}
+
+	keys := make([]string, len(sessionIds))
+	for i, sessionId := range sessionIds {
+		keys[i] = r.sessionKey(sessionId)
+	}
+	values, err := r.client.MGet(r.ctx, keys...).Result()
+	if err != nil {
+		r.logger.Errorln("Error while fetching Sessions: ", err)
+		return nil, err
+	}
+
+	var sessions []auth.SessionSchema
+	var expired []any
+	for i, value := range values {
+		data, ok := value.(string)
+		if !ok {
+			expired = append(expired, sessionIds[i])
+			continue
+		}
+		var session storedSession
+		if err := json.Unmarshal([]byte(data), &session); err != nil {
+			r.logger.Errorln("Error while decoding Session: ", err)
+			return nil, err
+		}
+		sessions = append(sessions, toSchema(session))
+	}
+
+	// Sessions expire on their own; forget their ids lazily.
+	if len(expired) > 0 {
+		if err := r.client.SRem(r.ctx, userSessionsKey, expired...).Err(); err != nil {
+			r.logger.Errorln("Error while removing expired Sessions: ", err)
+			return nil, err
+		}
+	}
+
+	r.logger.Debugf("Sessions: %+v\n", sessions)
+	return sessions, nil
+}
+
+func (r *redisSessionAdapterImpl) SetSession(
+	session auth.SessionSchema,
+) error {
+	key := r.sessionKey(session.ID)
+	err := r.client.Watch(r.ctx, func(tx *redis.Tx) error {
+		existing, err := tx.Exists(r.ctx, key).Result()
+		if err != nil {
+			return err
+		}
+		if existing > 0 {
+			return auth.NewGuamError(AUTH_DUPLICATE_SESSION_ID, session.ID)
+		}
+		_, err = tx.TxPipelined(r.ctx, func(pipe redis.Pipeliner) error {
+			return r.setStoredSession(r.ctx, pipe, fromSchema(session))
+		})
+		return err
+	}, key)
+	if errors.Is(err, redis.TxFailedErr) {
+		// The key was written since it was found missing.
+		err = auth.NewGuamError(AUTH_DUPLICATE_SESSION_ID, session.ID)
+	}
+	if err != nil {
+		r.logger.Errorln("Error while inserting Session: ", err)
+		return err
+	}
+	return nil
+}
+
+func (r *redisSessionAdapterImpl) DeleteSession(
+	sessionId string,
+) error {
+	session, err := r.getStoredSession(r.ctx, r.client, sessionId)
+	if err != nil {
+		r.logger.Errorln("Error while deleting session: ", err)
+		return err
+	}
+	if session == nil {
+		return nil
+	}
+
+	_, err = r.client.TxPipelined(r.ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(r.ctx, r.sessionKey(sessionId))
+		pipe.SRem(r.ctx, r.userSessionsKey(session.UserID), sessionId)
+		return nil
+	})
+	if err != nil {
+		r.logger.Errorln("Error while deleting session: ", err)
+		return err
+	}
+	return nil
+}
+
+func (r *redisSessionAdapterImpl) DeleteSessionsByUserId(
+	userId string,
+) error {
+	userSessionsKey := r.userSessionsKey(userId)
+	sessionIds, err := r.client.SMembers(r.ctx, userSessionsKey).Result()
+	if err != nil {
+		r.logger.Errorln("Error while deleting session: ", err)
+		return err
+	}
+
+	keys := make([]string, 0, len(sessionIds)+1)
+	for _, sessionId := range sessionIds {
+		keys = append(keys, r.sessionKey(sessionId))
+	}
+	keys = append(keys, userSessionsKey)
+
+	if err := r.client.Del(r.ctx, keys...).Err(); err != nil {
+		r.logger.Errorln("Error while deleting session: ", err)
+		return err
+	}
+	return nil
+}
+
+func (r *redisSessionAdapterImpl) UpdateSession(
+	sessionId string,
+	partialSession map[string]any,
+) error {
+	key := r.sessionKey(sessionId)
+	err := r.client.Watch(r.ctx, func(tx *redis.Tx) error {
+		session, err := r.getStoredSession(r.ctx, tx, sessionId)
+		if err != nil || session == nil {
+			return err
+		}
+		previousUserId := session.UserID
+
+		if err := applyPartialSession(session, partialSession); err != nil {
+			return err
+		}
+
+		_, err = tx.TxPipelined(r.ctx, func(pipe redis.Pipeliner) error {
+			if session.ID != sessionId {
+				pipe.Del(r.ctx, key)
+			}
+			if session.ID != sessionId || session.UserID != previousUserId {
+				pipe.SRem(r.ctx, r.userSessionsKey(previousUserId), sessionId)
+			}
+			return r.setStoredSession(r.ctx, pipe, *session)
+		})
+		return err
+	}, key)
+	if err != nil {
+		r.logger.Errorln("Error while updating session: ", err)
+		return err
+	}
+	return nil
+}
+
+// applyPartialSession copies the values of partialSession onto session. Keys
+// that aren't session columns are stored as attributes.
+func applyPartialSession(session *storedSession, partialSession map[string]any) error {
+	for key, value := range partialSession {
+		var err error
+		switch key {
+		case "id":
+			session.ID, err = toString(value)
+		case "user_id":
+			session.UserID, err = toString(value)
+		case "active_expires":
+			session.ActiveExpires, err = toInt64(value)
+		case "idle_expires":
+			session.IdleExpires, err = toInt64(value)
+		default:
+			if session.Attributes == nil {
+				session.Attributes = make(map[string]any)
+			}
+			session.Attributes[key] = value
+		}
+		if err != nil {
+			return fmt.Errorf("redis: invalid value for %s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+func toString(value any) (string, error) {
+	if s, ok := value.(string); ok {
+		return s, nil
+	}
+	return "", fmt.Errorf("expected a string, got %T", value)
+}
+
+func toInt64(value any) (int64, error) {
+	switch v := value.(type) {
+	case int64:
+		return v, nil
+	case int:
+		return int64(v), nil
+	case int32:
+		return int64(v), nil
+	case float64:
+		return int64(v), nil
+	}
+	return 0, fmt.Errorf("expected an integer, got %T", value)
+}
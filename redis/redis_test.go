@@ -0,0 +1,321 @@
+package redis
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/seatedro/guam-adapters/adaptertest"
+	"github.com/seatedro/guam/auth"
+	"github.com/seatedro/guam/utils"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// setup starts an in-memory Redis server, so tests don't share state.
+func setup(t *testing.T) (*miniredis.Miniredis, SessionAdapter) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	adapter := RedisSessionAdapter(context.Background(), client, Prefixes{}, false)
+
+	return server, adapter
+}
+
+// createSession creates a session idling out after idle.
+func createSession(adapter SessionAdapter, userId string, idle time.Duration) auth.SessionSchema {
+	now := time.Now()
+	session := auth.SessionSchema{
+		ID:            utils.GenerateRandomString(5, ""),
+		UserID:        userId,
+		ActiveExpires: now.Add(idle / 2).UnixMilli(),
+		IdleExpires:   now.Add(idle).UnixMilli(),
+		Attributes: map[string]any{
+			"country": "NZ",
+		},
+	}
+	if err := adapter.SetSession(session); err != nil {
+		log.Fatal(err)
+	}
+	return session
+}
+
+func TestSetSession(t *testing.T) {
+	server, adapter := setup(t)
+
+	session := createSession(adapter, utils.GenerateRandomString(5, ""), time.Hour)
+
+	ttl := server.TTL("session:" + session.ID)
+	if ttl <= 59*time.Minute || ttl > time.Hour {
+		log.Fatalf("expected a TTL of an hour, got %s", ttl)
+	}
+	if ok, _ := server.SIsMember("user_sessions:"+session.UserID, session.ID); !ok {
+		log.Fatal("expected session to be in the user's sessions")
+	}
+}
+
+func TestSetSessionDuplicateSessionId(t *testing.T) {
+	_, adapter := setup(t)
+
+	session := createSession(adapter, utils.GenerateRandomString(5, ""), time.Hour)
+
+	duplicate := session
+	duplicate.UserID = utils.GenerateRandomString(5, "")
+	err := adapter.SetSession(duplicate)
+	var guamErr *auth.GuamError
+	if !errors.As(err, &guamErr) || guamErr.Message != AUTH_DUPLICATE_SESSION_ID {
+		log.Fatalf("expected %s, got %v", AUTH_DUPLICATE_SESSION_ID, err)
+	}
+
+	stored, err := adapter.GetSession(session.ID)
+	if err != nil || stored == nil || stored.UserID != session.UserID {
+		log.Fatalf("expected the first session to be kept, got %+v: %v", stored, err)
+	}
+	sessions, err := adapter.GetSessionsByUserId(duplicate.UserID)
+	if err != nil || sessions != nil {
+		log.Fatalf("expected no sessions for the second user, got %+v: %v", sessions, err)
+	}
+}
+
+func TestGetSession(t *testing.T) {
+	_, adapter := setup(t)
+
+	expected := createSession(adapter, utils.GenerateRandomString(5, ""), time.Hour)
+
+	session, err := adapter.GetSession(expected.ID)
+	if err != nil ||
+		session == nil ||
+		session.ID != expected.ID ||
+		session.UserID != expected.UserID ||
+		session.ActiveExpires != expected.ActiveExpires ||
+		session.IdleExpires != expected.IdleExpires ||
+		session.Attributes["country"] != "NZ" {
+		log.Fatalf("expected %+v, got %+v: %v", expected, session, err)
+	}
+}
+
+func TestGetSessionMissing(t *testing.T) {
+	_, adapter := setup(t)
+
+	session, err := adapter.GetSession(utils.GenerateRandomString(5, ""))
+	if err != nil || session != nil {
+		log.Fatalf("expected no session, got %+v: %v", session, err)
+	}
+}
+
+func TestSessionExpiresWhenIdle(t *testing.T) {
+	server, adapter := setup(t)
+
+	userId := utils.GenerateRandomString(5, "")
+	expired := createSession(adapter, userId, time.Minute)
+	live := createSession(adapter, userId, time.Hour)
+
+	server.FastForward(2 * time.Minute)
+
+	session, err := adapter.GetSession(expired.ID)
+	if err != nil || session != nil {
+		log.Fatalf("expected session to expire, got %+v: %v", session, err)
+	}
+
+	sessions, err := adapter.GetSessionsByUserId(userId)
+	if err != nil || len(sessions) != 1 || sessions[0].ID != live.ID {
+		log.Fatalf("expected only %s, got %+v: %v", live.ID, sessions, err)
+	}
+	if ok, _ := server.SIsMember("user_sessions:"+userId, expired.ID); ok {
+		log.Fatal("expected expired session to be removed from the user's sessions")
+	}
+}
+
+func TestUserSessionsExpireWithLastSession(t *testing.T) {
+	server, adapter := setup(t)
+
+	userId := utils.GenerateRandomString(5, "")
+	createSession(adapter, userId, time.Hour)
+	latest := createSession(adapter, userId, 2*time.Hour)
+	createSession(adapter, userId, time.Minute)
+
+	// Shorter sessions never cut the set's TTL short.
+	if ttl := server.TTL("user_sessions:" + userId); ttl <= 119*time.Minute || ttl > 2*time.Hour {
+		log.Fatalf("expected a TTL of two hours, got %s", ttl)
+	}
+
+	// Extending a session extends the set.
+	idleExpires := time.Now().Add(3 * time.Hour).UnixMilli()
+	if err := adapter.UpdateSession(latest.ID, map[string]any{"idle_expires": idleExpires}); err != nil {
+		log.Fatal(err)
+	}
+	if ttl := server.TTL("user_sessions:" + userId); ttl <= 179*time.Minute {
+		log.Fatalf("expected a TTL of three hours, got %s", ttl)
+	}
+
+	server.FastForward(3*time.Hour + time.Minute)
+	if server.Exists("user_sessions:" + userId) {
+		log.Fatal("expected the user's sessions to expire with the last one")
+	}
+}
+
+func TestGetSessionsByUserId(t *testing.T) {
+	_, adapter := setup(t)
+
+	userId := utils.GenerateRandomString(5, "")
+	createSession(adapter, userId, time.Hour)
+	createSession(adapter, userId, time.Hour)
+	createSession(adapter, utils.GenerateRandomString(5, ""), time.Hour)
+
+	sessions, err := adapter.GetSessionsByUserId(userId)
+	if err != nil || len(sessions) != 2 {
+		log.Fatalf("expected 2 sessions, got %+v: %v", sessions, err)
+	}
+
+	sessions, err = adapter.GetSessionsByUserId(utils.GenerateRandomString(5, ""))
+	if err != nil || sessions != nil {
+		log.Fatalf("expected no sessions, got %+v: %v", sessions, err)
+	}
+}
+
+func TestDeleteSession(t *testing.T) {
+	server, adapter := setup(t)
+
+	session := createSession(adapter, utils.GenerateRandomString(5, ""), time.Hour)
+
+	if err := adapter.DeleteSession(session.ID); err != nil {
+		log.Fatal(err)
+	}
+
+	deleted, err := adapter.GetSession(session.ID)
+	if err != nil || deleted != nil {
+		log.Fatal(err)
+	}
+	if ok, _ := server.SIsMember("user_sessions:"+session.UserID, session.ID); ok {
+		log.Fatal("expected session to be removed from the user's sessions")
+	}
+
+	// Deleting a missing session is not an error.
+	if err := adapter.DeleteSession(session.ID); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func TestDeleteSessionsByUserId(t *testing.T) {
+	server, adapter := setup(t)
+
+	userId := utils.GenerateRandomString(5, "")
+	createSession(adapter, userId, time.Hour)
+	createSession(adapter, userId, time.Hour)
+	other := createSession(adapter, utils.GenerateRandomString(5, ""), time.Hour)
+
+	if err := adapter.DeleteSessionsByUserId(userId); err != nil {
+		log.Fatal(err)
+	}
+
+	sessions, err := adapter.GetSessionsByUserId(userId)
+	if err != nil || sessions != nil {
+		log.Fatal(err)
+	}
+	if server.Exists("user_sessions:" + userId) {
+		log.Fatal("expected the user's sessions to be deleted")
+	}
+
+	session, err := adapter.GetSession(other.ID)
+	if err != nil || session == nil {
+		log.Fatal("expected other user's session to be kept: ", err)
+	}
+}
+
+func TestUpdateSession(t *testing.T) {
+	server, adapter := setup(t)
+
+	session := createSession(adapter, utils.GenerateRandomString(5, ""), time.Minute)
+
+	idleExpires := time.Now().Add(time.Hour).UnixMilli()
+	err := adapter.UpdateSession(session.ID, map[string]any{
+		"idle_expires": idleExpires,
+		"country":      "AU",
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	updated, err := adapter.GetSession(session.ID)
+	if err != nil ||
+		updated.IdleExpires != idleExpires ||
+		updated.ActiveExpires != session.ActiveExpires ||
+		updated.Attributes["country"] != "AU" {
+		log.Fatalf("expected session to be updated, got %+v: %v", updated, err)
+	}
+
+	// The TTL follows the new idle_expires.
+	if ttl := server.TTL("session:" + session.ID); ttl <= 59*time.Minute {
+		log.Fatalf("expected a TTL of an hour, got %s", ttl)
+	}
+}
+
+func TestUpdateSessionUserId(t *testing.T) {
+	_, adapter := setup(t)
+
+	session := createSession(adapter, utils.GenerateRandomString(5, ""), time.Hour)
+
+	userId := utils.GenerateRandomString(5, "")
+	if err := adapter.UpdateSession(session.ID, map[string]any{"user_id": userId}); err != nil {
+		log.Fatal(err)
+	}
+
+	sessions, err := adapter.GetSessionsByUserId(session.UserID)
+	if err != nil || sessions != nil {
+		log.Fatalf("expected no sessions for the previous user, got %+v: %v", sessions, err)
+	}
+	sessions, err = adapter.GetSessionsByUserId(userId)
+	if err != nil || len(sessions) != 1 {
+		log.Fatalf("expected 1 session for the new user, got %+v: %v", sessions, err)
+	}
+}
+
+func TestWithLogger(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	core, logs := observer.New(zap.DebugLevel)
+	adapter := RedisSessionAdapter(context.Background(), client, Prefixes{}, false, WithLogger(zap.New(core)))
+
+	server.SetError("unavailable")
+	if _, err := adapter.GetSession(utils.GenerateRandomString(5, "")); err == nil {
+		log.Fatal("expected an error")
+	}
+	if logs.FilterMessageSnippet("Error while fetching Session").Len() != 1 {
+		log.Fatalf("expected the error to be logged, got %v", logs.All())
+	}
+}
+
+func TestWithSlogLogger(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	var buf bytes.Buffer
+	adapter := RedisSessionAdapter(context.Background(), client, Prefixes{}, false,
+		WithSlogLogger(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))))
+
+	session := createSession(adapter, utils.GenerateRandomString(5, ""), time.Hour)
+	if _, err := adapter.GetSession(session.ID); err != nil {
+		log.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), session.ID) {
+		log.Fatalf("expected the session to be logged, got %s", buf.String())
+	}
+}
+
+func TestConformance(t *testing.T) {
+	adaptertest.RunSessions(t, func(t *testing.T) adaptertest.SessionAdapter {
+		_, adapter := setup(t)
+		return adapter
+	})
+}
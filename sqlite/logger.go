@@ -0,0 +1,27 @@
+package sqlite
+
+import (
+	"log/slog"
+
+	"github.com/seatedro/guam-adapters/internal/sqlutil"
+	"go.uber.org/zap"
+)
+
+// WithLogger logs through l instead of a logger built from debugMode. A nil l
+// keeps the default logger.
+func WithLogger(l *zap.Logger) Option {
+	if l == nil {
+		return sqlutil.WithLogger(nil)
+	}
+	return sqlutil.WithLogger(l.Sugar())
+}
+
+// WithSlogLogger logs through l instead of a logger built from debugMode.
+// Queries and results are logged at debug level, failures at error level. A
+// nil l keeps the default logger.
+func WithSlogLogger(l *slog.Logger) Option {
+	if l == nil {
+		return sqlutil.WithLogger(nil)
+	}
+	return sqlutil.WithLogger(sqlutil.SlogLogger{L: l})
+}
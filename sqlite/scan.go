@@ -0,0 +1,13 @@
+package sqlite
+
+import "strings"
+
+// convertValue converts a value read from a column of type databaseType.
+// SQLite stores booleans as integers, so values of BOOLEAN columns are
+// converted back.
+func convertValue(databaseType string, val any) (any, error) {
+	if n, ok := val.(int64); ok && strings.EqualFold(databaseType, "BOOLEAN") {
+		return n != 0, nil
+	}
+	return val, nil
+}
@@ -0,0 +1,27 @@
+package sqlite
+
+import "github.com/seatedro/guam-adapters/internal/sqlutil"
+
+// Projection lists the attributes read by GetSessionAndUser, on top of the
+// guam columns, which are always read.
+type Projection = sqlutil.Projection
+
+// WithSessionAndUserProjection limits GetSessionAndUser, which guam runs to
+// validate every session, to the attributes of projection.
+func WithSessionAndUserProjection(projection Projection) Option {
+	return sqlutil.WithSessionAndUserProjection(dialect, projection)
+}
+
+// WithUserColumns declares the columns of the user table, which getters read.
+// Without it, they are read from the database the first time they're needed,
+// and again after RefreshColumns.
+func WithUserColumns(columns ...string) Option {
+	return sqlutil.WithUserColumns(columns)
+}
+
+// WithSessionColumns declares the columns of the session table, which getters
+// read. Without it, they are read from the database the first time they're
+// needed, and again after RefreshColumns.
+func WithSessionColumns(columns ...string) Option {
+	return sqlutil.WithSessionColumns(columns)
+}
@@ -0,0 +1,104 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/seatedro/guam-adapters/internal/sqlutil"
+	"github.com/seatedro/guam/auth"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+type Tables struct {
+	User    string
+	Session string
+	Key     string
+
+	// UserColumns, SessionColumns and KeyColumns rename the columns of the
+	// guam schemas in tables that name them differently, e.g. {"id": "uid"}.
+	UserColumns    Columns
+	SessionColumns Columns
+	KeyColumns     Columns
+}
+
+// Columns maps the columns of a guam schema, named after their db tag, to the
+// columns storing them. Columns missing from the map keep their name.
+type Columns = sqlutil.Columns
+
+// Adapter is the guam adapter returned by SQLiteAdapter.
+type Adapter interface {
+	auth.AdapterWithGetter
+
+	// WithContext returns a copy of the adapter whose queries run with ctx
+	// instead of the context passed to SQLiteAdapter.
+	WithContext(ctx context.Context) Adapter
+
+	// RefreshColumns forgets the columns read from the database, so that
+	// getters read them again, e.g. after a migration. Columns declared with
+	// WithUserColumns and WithSessionColumns are kept.
+	RefreshColumns()
+}
+
+// Option configures the adapter returned by SQLiteAdapter.
+type Option = sqlutil.Option
+
+var driver = sqlutil.Driver{
+	Name:            "sqlite",
+	Dialect:         dialect,
+	Constraint:      constraint,
+	UndefinedColumn: undefinedColumn,
+	ConvertValue:    convertValue,
+}
+
+type sqliteAdapterImpl struct {
+	*sqlutil.Adapter
+}
+
+// SQLiteAdapter returns an adapter storing users, sessions and keys in db.
+// Foreign keys are only enforced by SQLite when enabled on every connection,
+// so db should be opened with the _pragma=foreign_keys(1) DSN parameter.
+//
+// The table names in tables are part of the program's configuration, so it
+// panics if one can't be escaped; check names coming from elsewhere with
+// ValidateName first.
+func SQLiteAdapter(
+	ctx context.Context,
+	db *sql.DB,
+	tables Tables,
+	debugMode bool,
+	opts ...Option,
+) Adapter {
+	return &sqliteAdapterImpl{sqlutil.NewAdapter(ctx, db, driver, sqlutil.Tables(tables), debugMode, opts...)}
+}
+
+func (s *sqliteAdapterImpl) WithContext(ctx context.Context) Adapter {
+	return &sqliteAdapterImpl{s.Adapter.WithContext(ctx)}
+}
+
+// constraint reports the constraint violated by err, if any.
+func constraint(err error) (sqlutil.Constraint, string) {
+	var sqliteErr *sqlite.Error
+	if !errors.As(err, &sqliteErr) {
+		return sqlutil.NoConstraint, ""
+	}
+	switch sqliteErr.Code() {
+	case sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY, sqlite3.SQLITE_CONSTRAINT_UNIQUE:
+		return sqlutil.UniqueConstraint, sqliteErr.Error()
+	case sqlite3.SQLITE_CONSTRAINT_FOREIGNKEY:
+		return sqlutil.ForeignKeyConstraint, sqliteErr.Error()
+	}
+	return sqlutil.NoConstraint, ""
+}
+
+// undefinedColumn reports whether err is from a query naming an unknown column.
+// sqlite reports those with the generic SQLITE_ERROR code, so the message is
+// checked.
+func undefinedColumn(err error) bool {
+	var sqliteErr *sqlite.Error
+	return errors.As(err, &sqliteErr) &&
+		sqliteErr.Code() == sqlite3.SQLITE_ERROR &&
+		strings.Contains(sqliteErr.Error(), "no such column")
+}